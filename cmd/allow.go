@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
+	"github.com/spf13/cobra"
+)
+
+var allowRemove bool
+
+var allowCmd = &cobra.Command{
+	Use:   "allow <script-name> [check...]",
+	Short: "Trust a script to run commands flagged as dangerous",
+	Long: `Trust a script to run commands flagged as dangerous.
+
+Before running a script, bashhub looks for destructive commands and asks
+for confirmation (--yes on the command line). Checks allowed for a script
+are no longer reported for it. Without checks, the script's allowlist and
+the available checks are printed.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script, err := database.GetScriptByName(db, args[0])
+		if err != nil {
+			log.Fatalf("Script '%s' not found", args[0])
+		}
+
+		if len(args) == 1 {
+			for _, check := range executor.Checks {
+				mark := " "
+				if executor.IsAllowed(script.AllowedChecks, check.ID) {
+					mark = "✔"
+				}
+				fmt.Printf("%s %-16s %s\n", mark, check.ID, check.Description)
+			}
+			return
+		}
+
+		checks, err := executor.ParseAllowlist(strings.Join(args[1:], " "))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if allowRemove {
+			script.AllowedChecks = executor.RemoveChecks(script.AllowedChecks, strings.Fields(checks))
+		} else {
+			script.AllowedChecks = executor.AllowChecks(script.AllowedChecks, strings.Fields(checks))
+		}
+
+		if err := database.UpdateScript(db, script); err != nil {
+			log.Fatalf("Failed to update script: %v", err)
+		}
+		if script.AllowedChecks == "" {
+			fmt.Printf("No checks allowed for '%s'\n", script.Name)
+		} else {
+			fmt.Printf("Allowed for '%s': %s\n", script.Name, script.AllowedChecks)
+		}
+	},
+}
+
+func init() {
+	allowCmd.Flags().BoolVar(&allowRemove, "remove", false, "Stop allowing the given checks")
+	rootCmd.AddCommand(allowCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var migrateStatus bool
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the bashhub database",
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Long: `Apply pending schema migrations.
+
+bashhub migrates its database automatically on start, so this is mostly
+useful with --status to see which migrations have been applied. Before
+migrating an existing database a backup is written next to it.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := dbPath()
+		db := database.OpenDB(path)
+
+		if migrateStatus {
+			states, err := database.MigrationStatus(db)
+			if err != nil {
+				log.Fatalf("Failed to read migration status: %v", err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+			for _, s := range states {
+				applied := "pending"
+				if s.AppliedAt != nil {
+					applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+			}
+			w.Flush()
+			return
+		}
+
+		applied, err := database.Migrate(db, path)
+		if err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date.")
+			return
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+	},
+}
+
+var dbPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the location of the database in use",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(dbPath())
+	},
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateStatus, "status", false, "List migrations and whether they have been applied")
+	dbCmd.AddCommand(migrateCmd, dbPathCmd)
+	rootCmd.AddCommand(dbCmd)
+}
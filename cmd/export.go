@@ -1,15 +1,14 @@
 package cmd
 
 import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/config"
 	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
 	"github.com/spf13/cobra"
 	"log"
-	"os"
-	"bufio"
-	"fmt"
 	"strings"
-	"github.com/jmoiron/sqlx"
-	"github.com/maccalsa/bashhub/internal/executor"
 )
 
 var placeholderOverrides []string
@@ -19,13 +18,14 @@ var exportCmd = &cobra.Command{
 	Short: "Export a script with placeholder substitutions",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		db := database.ConnectDB()
+		db := connectDB()
 		exportScript(db, args[0], placeholderOverrides)
 	},
 }
 
 func init() {
 	exportCmd.Flags().StringArrayVarP(&placeholderOverrides, "set", "s", []string{}, "Set placeholder values (key=value)")
+	addPlaceholderFlags(exportCmd)
 	rootCmd.AddCommand(exportCmd)
 }
 
@@ -35,8 +35,11 @@ func exportScript(db *sqlx.DB, scriptName string, overrides []string) {
 		log.Fatalf("Script not found: %v", err)
 	}
 
-	placeholders := executor.ParsePlaceholders(script.Content)
-	inputs := make(map[string]string)
+	placeholders, err := executor.ParsePlaceholders(script.Content)
+	if err != nil {
+		log.Fatalf("Invalid placeholder: %v", err)
+	}
+	set := make(map[string]string)
 
 	// First, parse command-line overrides
 	for _, override := range overrides {
@@ -44,20 +47,29 @@ func exportScript(db *sqlx.DB, scriptName string, overrides []string) {
 		if len(parts) != 2 {
 			log.Fatalf("Invalid placeholder override: %s. Use key=value format.", override)
 		}
-		inputs[parts[0]] = parts[1]
+		set[parts[0]] = parts[1]
 	}
 
-	reader := bufio.NewReader(os.Stdin)
+	inputs, _, err := collectPlaceholderValues(placeholders, set)
+	if err != nil {
+		log.Fatalf("Failed to read placeholder values: %v", err)
+	}
 
-	for _, ph := range placeholders {
-		if _, exists := inputs[ph]; !exists {
-			fmt.Printf("Enter value for '%s': ", ph)
-			input, _ := reader.ReadString('\n')
-			inputs[ph] = strings.TrimSpace(input)
-		}
+	if err := resolvePlaceholders(placeholders, inputs); err != nil {
+		log.Fatalf("Invalid placeholder value: %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
 	}
+	interpreter := executor.ResolveInterpreter(script.Content, script.Language, script.Interpreter, cfg.Interpreters)
 
-	finalContent := executor.ReplacePlaceholders(script.Content, inputs)
+	quote := !script.RawPlaceholders && executor.IsShell(interpreter)
+	finalContent, err := executor.ReplacePlaceholders(script.Content, inputs, quote)
+	if err != nil {
+		log.Fatalf("Failed to substitute placeholders: %v", err)
+	}
 
 	fmt.Println(finalContent)
 }
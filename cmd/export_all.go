@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/scriptfile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportDir    string
+	exportBundle string
+)
+
+var exportAllCmd = &cobra.Command{
+	Use:   "export-all (--dir <folder> | --bundle <file>)",
+	Short: "Export the whole library to a folder or a bundle",
+	Long: `Export the whole library to a folder or a bundle.
+
+--dir writes every script to <category>/<name><ext>, with its description
+and settings in a "# bashhub:" header, so the folder can be imported again.
+
+--bundle writes a single file with every script and its version history:
+lib.json, or lib.tar.gz holding the same folder layout plus the JSON. Import
+it with "bashhub import lib.tar.gz".`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (exportDir == "") == (exportBundle == "") {
+			log.Fatalf("Use exactly one of --dir and --bundle")
+		}
+
+		db := connectDB()
+		scripts, err := database.GetScripts(db)
+		if err != nil {
+			log.Fatalf("Failed to load scripts: %v", err)
+		}
+
+		if exportDir != "" {
+			exportToDir(scripts, exportDir)
+		} else {
+			exportToBundle(db, scripts, exportBundle)
+		}
+	},
+}
+
+func init() {
+	exportAllCmd.Flags().StringVar(&exportDir, "dir", "", "Write scripts as files into this folder")
+	exportAllCmd.Flags().StringVar(&exportBundle, "bundle", "", "Write a .json or .tar.gz bundle")
+	rootCmd.AddCommand(exportAllCmd)
+}
+
+func exportToDir(scripts []database.Script, dir string) {
+	for _, script := range scripts {
+		relPath, content := scriptfile.ScriptFile(script)
+		path := filepath.Join(dir, filepath.FromSlash(relPath))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Fatalf("Failed to create folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), scriptfile.FileMode(content)); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	fmt.Printf("Exported %d scripts to %s\n", len(scripts), dir)
+}
+
+func exportToBundle(db *sqlx.DB, scripts []database.Script, path string) {
+	entries := make([]scriptfile.BundleScript, 0, len(scripts))
+	for _, script := range scripts {
+		versions, err := database.GetScriptVersions(db, script.ID)
+		if err != nil {
+			log.Fatalf("Failed to load versions of '%s': %v", script.Name, err)
+		}
+		entries = append(entries, scriptfile.BundleScript{Script: script, Versions: versions})
+	}
+
+	if err := scriptfile.WriteBundle(path, scriptfile.NewBundle(entries)); err != nil {
+		log.Fatalf("Failed to write bundle: %v", err)
+	}
+	fmt.Printf("Exported %d scripts to %s\n", len(scripts), path)
+}
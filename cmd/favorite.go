@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var favoriteRemove bool
+
+var favoriteCmd = &cobra.Command{
+	Use:     "favorite <script-name>",
+	Aliases: []string{"fav", "pin"},
+	Short:   "Pin a script to the favorites at the top of the TUI tree",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script := mustGetScript(db, args[0])
+
+		if err := database.SetFavorite(db, script.ID, !favoriteRemove); err != nil {
+			log.Fatalf("Failed to update favorites: %v", err)
+		}
+		if favoriteRemove {
+			fmt.Printf("Removed '%s' from favorites\n", script.Name)
+		} else {
+			fmt.Printf("Added '%s' to favorites\n", script.Name)
+		}
+	},
+}
+
+func init() {
+	favoriteCmd.Flags().BoolVar(&favoriteRemove, "remove", false, "Unpin the script")
+	rootCmd.AddCommand(favoriteCmd)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyStatus     string
+	historySince      string
+	historyUntil      string
+	historyLimit      int
+	historyShowOutput bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [script-name]",
+	Short: "Show the execution history of scripts",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := database.RunFilter{Status: historyStatus, Limit: historyLimit}
+		if len(args) == 1 {
+			filter.ScriptName = args[0]
+		}
+
+		switch historyStatus {
+		case "", database.RunStatusRunning, database.RunStatusSuccess, database.RunStatusFailed:
+		default:
+			log.Fatalf("Invalid status '%s'. Use running, success or failed.", historyStatus)
+		}
+
+		var err error
+		if filter.Since, err = parseHistoryTime(historySince); err != nil {
+			log.Fatalf("Invalid --since value: %v", err)
+		}
+		if filter.Until, err = parseHistoryTime(historyUntil); err != nil {
+			log.Fatalf("Invalid --until value: %v", err)
+		}
+
+		db := connectDB()
+		runs, err := database.GetRuns(db, filter)
+		if err != nil {
+			log.Fatalf("Failed to load history: %v", err)
+		}
+
+		printHistory(runs, historyShowOutput)
+	},
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historyStatus, "status", "", "Only show runs with this status (running, success, failed)")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only show runs started at or after this date (YYYY-MM-DD, RFC3339 or a duration such as 24h)")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "Only show runs started before this date (YYYY-MM-DD, RFC3339 or a duration such as 24h)")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Maximum number of runs to show (0 for all)")
+	historyCmd.Flags().BoolVarP(&historyShowOutput, "output", "o", false, "Print the captured output of each run")
+	rootCmd.AddCommand(historyCmd)
+}
+
+// parseHistoryTime accepts a date, a timestamp or a duration relative to now.
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse '%s'", value)
+}
+
+func printHistory(runs []database.Run, showOutput bool) {
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSCRIPT\tSTARTED\tDURATION\tSTATUS\tEXIT\tINPUTS")
+	for _, run := range runs {
+		exitCode := "-"
+		if run.ExitCode.Valid {
+			exitCode = fmt.Sprint(run.ExitCode.Int64)
+		}
+		duration := "-"
+		if run.FinishedAt.Valid {
+			duration = run.Duration().Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			run.ID, run.ScriptName, run.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			duration, run.Status(), exitCode, run.Inputs)
+
+		if showOutput && run.Output != "" {
+			w.Flush()
+			fmt.Println(strings.TrimRight(run.Output, "\r\n"))
+			fmt.Println()
+		}
+	}
+	w.Flush()
+}
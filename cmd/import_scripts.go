@@ -2,64 +2,272 @@ package cmd
 
 import (
 	"fmt"
+	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
-	"github.com/maccalsa/bashhub/internal/tui"
-	"github.com/maccalsa/bashhub/internal/database"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/scriptfile"
+	"github.com/maccalsa/bashhub/internal/tui"
 	"github.com/spf13/cobra"
-	"log"
+)
+
+// Conflict policies for scripts whose name is already taken
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictRename    = "rename"
+)
+
+// importCategory is used for files at the top of the imported folder
+const importCategory = "Imported"
+
+var (
+	importOnConflict string
+	importDryRun     bool
+	importInclude    []string
+	importExclude    []string
 )
 
 var importCmd = &cobra.Command{
-	Use:   "import",
-	Short: "Import scripts from a folder",
-	Args:  cobra.ExactArgs(1),
+	Use:   "import <folder|bundle>",
+	Short: "Import scripts from a folder and its subfolders, or a bundle",
+	Long: `Import scripts from a folder and its subfolders.
+
+Each file's folder, relative to the imported one, becomes its category and
+the file name without extension its name. Header comments override both:
+
+  # bashhub: description=Nightly backup, category=ops/db, tags=backup cron
+
+Files that fail to import are reported and skipped.
+
+A bundle written by export-all --bundle (.json or .tar.gz) is imported with
+the version history of each new script.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		db := database.ConnectDB()
-		importFolder(db, args[0])
+		switch importOnConflict {
+		case conflictSkip, conflictOverwrite, conflictRename:
+		default:
+			log.Fatalf("Invalid --on-conflict '%s'. Use skip, overwrite or rename.", importOnConflict)
+		}
+		for _, pattern := range append(importInclude, importExclude...) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				log.Fatalf("Invalid pattern '%s': %v", pattern, err)
+			}
+		}
+
+		db := connectDB()
+		var failed int
+		if info, err := os.Stat(args[0]); err == nil && !info.IsDir() && scriptfile.IsBundle(args[0]) {
+			failed = importBundle(db, args[0])
+		} else {
+			failed = importFolder(db, args[0])
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
+	importCmd.Flags().StringVar(&importOnConflict, "on-conflict", conflictSkip, "What to do when a script name exists: skip, overwrite or rename")
+	importCmd.Flags().BoolVarP(&importDryRun, "dry-run", "n", false, "Report what would be imported without changing anything")
+	importCmd.Flags().StringArrayVarP(&importInclude, "include", "i", nil, "Only import files matching this glob (repeatable)")
+	importCmd.Flags().StringArrayVarP(&importExclude, "exclude", "x", nil, "Skip files and folders matching this glob (repeatable)")
 	rootCmd.AddCommand(importCmd)
 }
 
-func importFolder(db *sqlx.DB, folderPath string) {
-	files, err := os.ReadDir(folderPath)
+// importer applies the conflict policy and reports on each imported file.
+type importer struct {
+	db     *sqlx.DB
+	taken  map[string]int64
+	counts map[string]int
+}
+
+func newImporter(db *sqlx.DB) *importer {
+	existing, err := database.GetScripts(db)
 	if err != nil {
-		log.Fatalf("Failed to read folder: %v", err)
+		log.Fatalf("Failed to load scripts: %v", err)
 	}
+	im := &importer{db: db, taken: make(map[string]int64), counts: make(map[string]int)}
+	for _, script := range existing {
+		im.taken[script.Name] = script.ID
+	}
+	return im
+}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue // Ignore directories explicitly
+func (im *importer) report(action, relPath, detail string) {
+	im.counts[action]++
+	fmt.Printf("%-10s %s%s\n", action, relPath, detail)
+}
+
+// add saves a script read from relPath. A script read from a file comes
+// with its header, whose fields alone replace those of a script it
+// overwrites; a bundle's has none, as it carries every field. Versions
+// from a bundle are kept when the script is new.
+func (im *importer) add(relPath string, script database.Script, header *scriptfile.Header, versions []database.ScriptVersion) {
+	action := "added"
+	if id, exists := im.taken[script.Name]; exists {
+		switch importOnConflict {
+		case conflictSkip:
+			im.report("skipped", relPath, " (exists)")
+			return
+		case conflictOverwrite:
+			action = "overwrote"
+			if header != nil {
+				existing, err := database.GetScriptByID(im.db, id)
+				if err != nil {
+					im.report("failed", relPath, ": "+err.Error())
+					return
+				}
+				script = scriptfile.Merge(existing, script, *header)
+			}
+			script.ID = id
+		case conflictRename:
+			script.Name = freeName(im.taken, script.Name)
+			action = "renamed"
 		}
+	}
+	if action != "overwrote" && header != nil && !header.Keys["description"] {
+		script.Description = fmt.Sprintf("Imported from %s", filepath.ToSlash(relPath))
+	}
 
-		filePath := filepath.Join(folderPath, file.Name())
-		content, err := os.ReadFile(filePath)
+	if !importDryRun {
+		var err error
+		if script.ID != 0 {
+			err = database.UpdateScript(im.db, script)
+		} else {
+			script, err = database.RestoreScript(im.db, script, versions)
+		}
 		if err != nil {
-			log.Fatalf("Failed to read file: %v", err)
+			im.report("failed", relPath, ": "+err.Error())
+			return
+		}
+	}
+	im.taken[script.Name] = script.ID
+
+	if action == "renamed" {
+		im.report(action, relPath, " → "+script.Name)
+	} else {
+		im.report(action, relPath, "")
+	}
+}
+
+// summary prints the totals and returns the number of failures.
+func (im *importer) summary() int {
+	summary := "Imported"
+	if importDryRun {
+		summary = "Dry run, would import"
+	}
+	fmt.Printf("\n%s %d new, %d overwritten, %d renamed; %d skipped, %d failed\n", summary,
+		im.counts["added"], im.counts["overwrote"], im.counts["renamed"], im.counts["skipped"], im.counts["failed"])
+	return im.counts["failed"]
+}
+
+// importFolder imports every file below folderPath, printing one line per
+// file and a summary. It returns the number of files that failed.
+func importFolder(db *sqlx.DB, folderPath string) int {
+	im := newImporter(db)
+
+	err := filepath.WalkDir(folderPath, func(path string, entry fs.DirEntry, walkErr error) error {
+		relPath, _ := filepath.Rel(folderPath, path)
+		if walkErr != nil {
+			im.report("failed", relPath, ": "+walkErr.Error())
+			return nil
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") || matchesAny(importExclude, relPath) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() {
+			return nil
+		}
+		if len(importInclude) > 0 && !matchesAny(importInclude, relPath) {
+			return nil
 		}
-		
-		scriptName := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
-		scriptLanguage := tui.DetectLanguage(string(content))
 
-		script := database.Script{
-			Name: scriptName,
-			Description: fmt.Sprintf("Imported from %s", file.Name()),
-			Content:     string(content),
-			Category:    "Imported",
-			Language:    scriptLanguage,
+		script, header, err := readScriptFile(path, relPath)
+		if err != nil {
+			im.report("failed", relPath, ": "+err.Error())
+			return nil
 		}
+		im.add(relPath, script, &header, nil)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to read folder: %v", err)
+	}
 
-		if err := database.CreateScript(db, script); err != nil {
-			log.Printf("Failed to import script %s: %v", scriptName, err)
-		} else {
-			log.Printf("Successfully imported script %s", scriptName)
+	return im.summary()
+}
+
+// importBundle imports the scripts of a bundle written by export-all, with
+// their version history. Filters match the paths scripts have in the bundle.
+func importBundle(db *sqlx.DB, bundlePath string) int {
+	bundle, err := scriptfile.ReadBundle(bundlePath)
+	if err != nil {
+		log.Fatalf("Failed to read bundle: %v", err)
+	}
+
+	im := newImporter(db)
+	for _, entry := range bundle.Scripts {
+		relPath, _ := scriptfile.ScriptFile(entry.Script)
+		if matchesAny(importExclude, relPath) || (len(importInclude) > 0 && !matchesAny(importInclude, relPath)) {
+			continue
 		}
+		script := entry.Script
+		script.ID = 0
+		im.add(relPath, script, nil, entry.Versions)
+	}
+	return im.summary()
+}
+
+// readScriptFile builds a script from a file and its bashhub header.
+func readScriptFile(path, relPath string) (database.Script, scriptfile.Header, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return database.Script{}, scriptfile.Header{}, err
+	}
+
+	script, header, err := scriptfile.ParseFile(filepath.ToSlash(relPath), data, importCategory)
+	if err != nil {
+		return script, header, err
 	}
+	script.Language = tui.DetectLanguageForFile(relPath, script.Content)
+	return script, header, nil
 }
 
+// freeName appends the first free "-N" suffix to name.
+func freeName(taken map[string]int64, name string) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if _, exists := taken[candidate]; !exists {
+			return candidate
+		}
+	}
+}
 
+// matchesAny reports whether a glob matches relPath. Patterns with a slash
+// match the whole relative path, others just the last element.
+func matchesAny(patterns []string, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range patterns {
+		target := relPath
+		if !strings.Contains(pattern, "/") {
+			target = filepath.Base(relPath)
+		}
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/config"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
+	"github.com/maccalsa/bashhub/internal/tui"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	listCategory string
+	listLanguage string
+	listTags     []string
+	listRecent   bool
+	listTop      bool
+	listFavorite bool
+	listLimit    int
+	listFormat   string
+	listJSON     bool
+	showColor    string
+	showJSON     bool
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List scripts in the library",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if listJSON {
+			listFormat = "json"
+		}
+		switch listFormat {
+		case "table", "plain", "json":
+		default:
+			log.Fatalf("Invalid format '%s'. Use table, plain or json.", listFormat)
+		}
+		if listRecent && listTop {
+			log.Fatal("Use either --recent or --top")
+		}
+
+		tags, err := database.NormalizeTags(listTags)
+		if err != nil {
+			log.Fatalf("Invalid --tag: %v", err)
+		}
+
+		db := connectDB()
+		scripts, err := database.GetScripts(db)
+		if err != nil {
+			log.Fatalf("Failed to load scripts: %v", err)
+		}
+		favorites, err := database.GetFavorites(db)
+		if err != nil {
+			log.Fatalf("Failed to load favorites: %v", err)
+		}
+
+		filtered := []database.Script{}
+		for _, script := range scripts {
+			if listCategory != "" && !database.InCategory(strings.ToLower(script.Category), strings.ToLower(listCategory)) {
+				continue
+			}
+			if listLanguage != "" && !strings.EqualFold(script.Language, listLanguage) {
+				continue
+			}
+			if !script.HasTags(tags) {
+				continue
+			}
+			if listFavorite && !favorites[script.ID] {
+				continue
+			}
+			filtered = append(filtered, script)
+		}
+
+		if listRecent || listTop {
+			printUsage(db, filtered)
+			return
+		}
+
+		switch listFormat {
+		case "json":
+			printJSON(filtered)
+		case "plain":
+			// One name per line, for fzf and friends
+			for _, script := range filtered {
+				fmt.Println(script.Name)
+			}
+		default:
+			if len(filtered) == 0 {
+				fmt.Println("No scripts found.")
+				return
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tCATEGORY\tLANGUAGE\tTAGS\tDESCRIPTION")
+			for _, script := range filtered {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", script.Name, script.Category, script.Language, strings.Join(script.Tags, ","), firstLine(script.Description))
+			}
+			w.Flush()
+		}
+	},
+}
+
+// usageEntry is a script with its usage, for list --recent and --top
+type usageEntry struct {
+	database.Script
+	database.Usage
+}
+
+// printUsage lists the scripts that have been run, most recent or most used
+// first, in listFormat.
+func printUsage(db *sqlx.DB, scripts []database.Script) {
+	usage, err := database.GetUsage(db)
+	if err != nil {
+		log.Fatalf("Failed to load run history: %v", err)
+	}
+
+	entries := []usageEntry{}
+	for _, script := range scripts {
+		if u, ok := usage[script.ID]; ok {
+			entries = append(entries, usageEntry{script, u})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Usage, entries[j].Usage
+		if listTop && a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.LastRun.After(b.LastRun)
+	})
+	if listLimit > 0 && len(entries) > listLimit {
+		entries = entries[:listLimit]
+	}
+
+	switch listFormat {
+	case "json":
+		printJSON(entries)
+	case "plain":
+		for _, entry := range entries {
+			fmt.Println(entry.Name)
+		}
+	default:
+		if len(entries) == 0 {
+			fmt.Println("No scripts have been run yet.")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCATEGORY\tRUNS\tLAST RUN")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", entry.Name, entry.Category, entry.Runs, entry.LastRun.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+	}
+}
+
+// placeholderInfo is the JSON form of an executor.Placeholder
+type placeholderInfo struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Default *string  `json:"default,omitempty"`
+	Choices []string `json:"choices,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show <script-name>",
+	Short: "Show a script's details, placeholders and content",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script := mustGetScript(db, args[0])
+
+		placeholders, err := executor.ParsePlaceholders(script.Content)
+		if err != nil {
+			log.Fatalf("Invalid placeholder: %v", err)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		interpreter := executor.ResolveInterpreter(script.Content, script.Language, script.Interpreter, cfg.Interpreters)
+
+		if showJSON {
+			infos := []placeholderInfo{}
+			for _, ph := range placeholders {
+				info := placeholderInfo{Name: ph.Name, Type: string(ph.Type), Choices: ph.Choices}
+				if ph.HasDefault {
+					info.Default = &ph.Default
+				}
+				if ph.Pattern != nil {
+					info.Pattern = ph.Pattern.String()
+				}
+				infos = append(infos, info)
+			}
+			printJSON(struct {
+				database.Script
+				Command      []string          `json:"command"`
+				Placeholders []placeholderInfo `json:"placeholders"`
+			}{script, interpreter, infos})
+			return
+		}
+
+		var color bool
+		switch showColor {
+		case "auto":
+			color = term.IsTerminal(int(os.Stdout.Fd()))
+		case "always":
+			color = true
+		case "never":
+		default:
+			log.Fatalf("Invalid --color '%s'. Use auto, always or never.", showColor)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Name:\t%s\n", script.Name)
+		fmt.Fprintf(w, "Category:\t%s\n", script.Category)
+		fmt.Fprintf(w, "Language:\t%s\n", script.Language)
+		if len(script.Tags) > 0 {
+			fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(script.Tags, ", "))
+		}
+		fmt.Fprintf(w, "Command:\t%s\n", strings.Join(interpreter, " "))
+		if script.Description != "" {
+			fmt.Fprintf(w, "Description:\t%s\n", script.Description)
+		}
+		w.Flush()
+
+		if len(placeholders) > 0 {
+			fmt.Println("\nPlaceholders:")
+			for _, ph := range placeholders {
+				if hint := ph.Describe(); hint != "" {
+					fmt.Printf("  %s (%s)\n", ph.Name, hint)
+				} else {
+					fmt.Printf("  %s\n", ph.Name)
+				}
+			}
+		}
+
+		content := script.Content
+		if color {
+			content = tui.HighlightANSI(content, script.Language)
+		}
+		fmt.Printf("\n%s", content)
+		if !strings.HasSuffix(script.Content, "\n") {
+			fmt.Println()
+		}
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&listCategory, "category", "c", "", "Only list scripts in this category and its subcategories")
+	listCmd.Flags().StringVarP(&listLanguage, "language", "l", "", "Only list scripts in this language")
+	listCmd.Flags().StringSliceVarP(&listTags, "tag", "t", nil, "Only list scripts with this tag (repeatable, all must match)")
+	listCmd.Flags().BoolVar(&listFavorite, "favorites", false, "Only list favorite scripts")
+	listCmd.Flags().BoolVar(&listRecent, "recent", false, "List the scripts run most recently, newest first")
+	listCmd.Flags().BoolVar(&listTop, "top", false, "List the scripts run most often")
+	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 10, "Maximum number of scripts with --recent or --top (0 for all)")
+	listCmd.Flags().StringVarP(&listFormat, "format", "f", "table", "Output format: table, plain (names only) or json")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Shorthand for --format json")
+	showCmd.Flags().StringVar(&showColor, "color", "auto", "Highlight the content: auto, always or never")
+	showCmd.Flags().BoolVar(&showJSON, "json", false, "Print the script as JSON")
+	rootCmd.AddCommand(listCmd, showCmd)
+}
+
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Fatalf("Failed to write JSON: %v", err)
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/config"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// matrixTarget is one combination of matrix values a script runs with
+type matrixTarget struct {
+	values map[string]string
+	// label names the target in output prefixes and the summary, with
+	// sensitive values masked. runMatrix sets it.
+	label string
+}
+
+// matrixVariable is a placeholder with the values it takes across targets
+type matrixVariable struct {
+	name   string
+	values []string
+}
+
+// loadMatrix builds the targets of a matrix run from --matrix flags and a
+// matrix file. Every combination of the variables' values is a target; a
+// file listing combinations contributes those as they are. It returns nil
+// without a matrix.
+func loadMatrix(flags []string, file string) ([]matrixTarget, error) {
+	if len(flags) == 0 && file == "" {
+		return nil, nil
+	}
+
+	combinations := []map[string]string{{}}
+	if file != "" {
+		var err error
+		if combinations, err = readMatrixFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	var variables []matrixVariable
+	for _, flag := range flags {
+		name, list, ok := strings.Cut(flag, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --matrix '%s', use name=value1,value2", flag)
+		}
+		var values []string
+		for _, value := range strings.Split(list, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("--matrix %s has no values", name)
+		}
+		variables = append(variables, matrixVariable{name, values})
+	}
+	combinations, err := combine(combinations, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(combinations) == 0 {
+		return nil, errors.New("the matrix has no targets")
+	}
+	targets := make([]matrixTarget, len(combinations))
+	for i, values := range combinations {
+		targets[i] = matrixTarget{values: values}
+	}
+	return targets, nil
+}
+
+// readMatrixFile reads a .yaml or .json matrix: either a mapping of
+// variables to lists of values, combined like --matrix, or a list of
+// mappings, each one target.
+func readMatrixFile(path string) ([]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	default:
+		return nil, fmt.Errorf("%s: unsupported matrix file type '%s', use .yaml or .json", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var combinations []map[string]string
+	switch m := raw.(type) {
+	case map[string]interface{}:
+		var variables []matrixVariable
+		for name, list := range m {
+			items, ok := list.([]interface{})
+			if !ok {
+				items = []interface{}{list}
+			}
+			values, err := scalarList(items)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+			}
+			variables = append(variables, matrixVariable{name, values})
+		}
+		sort.Slice(variables, func(i, j int) bool { return variables[i].name < variables[j].name })
+		combinations, err = combine([]map[string]string{{}}, variables)
+	case []interface{}:
+		for i, item := range m {
+			entry, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: entry %d must be a mapping of placeholder values", path, i+1)
+			}
+			values, err := scalarValues(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+			}
+			combinations = append(combinations, values)
+		}
+	default:
+		return nil, fmt.Errorf("%s: expected a mapping of lists or a list of mappings", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return combinations, nil
+}
+
+// scalarList converts decoded YAML or JSON list items to strings.
+func scalarList(items []interface{}) ([]string, error) {
+	values := make([]string, len(items))
+	for i, item := range items {
+		scalar, err := scalarValues(map[string]interface{}{"value": item})
+		if err != nil {
+			return nil, errors.New("values must be strings, numbers or booleans")
+		}
+		values[i] = scalar["value"]
+	}
+	return values, nil
+}
+
+// combine extends every combination with every value of each variable.
+func combine(combinations []map[string]string, variables []matrixVariable) ([]map[string]string, error) {
+	for _, variable := range variables {
+		var next []map[string]string
+		for _, combination := range combinations {
+			if _, ok := combination[variable.name]; ok {
+				return nil, fmt.Errorf("matrix variable '%s' is given twice", variable.name)
+			}
+			for _, value := range variable.values {
+				extended := map[string]string{variable.name: value}
+				for name, v := range combination {
+					extended[name] = v
+				}
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
+
+// matrixLabel names a target by its values, e.g. "host=a region=eu".
+func matrixLabel(values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + values[name]
+	}
+	return strings.Join(parts, " ")
+}
+
+// matrixResult is how a target's run ended
+type matrixResult struct {
+	result  executor.Result
+	err     error
+	skipped bool
+}
+
+// runMatrix runs script once per target, at most runConcurrency at a time,
+// and exits with 0 if every target succeeded, 1 otherwise. Values that are
+// the same for every target are resolved, and prompted for, once; so is a
+// fallback for a matrix variable some targets leave out. Targets run without
+// a terminal or stdin, each line of their output prefixed with the target.
+func runMatrix(db *sqlx.DB, script database.Script, placeholders []executor.Placeholder, inputs, sources map[string]string, targets []matrixTarget) {
+	if runConcurrency < 1 {
+		log.Fatal("--concurrency must be at least 1")
+	}
+
+	byName := make(map[string]executor.Placeholder)
+	for _, ph := range placeholders {
+		byName[ph.Name] = ph
+	}
+	// Only variables every target sets need no value of their own
+	setBy := make(map[string]int)
+	for _, target := range targets {
+		for name := range target.values {
+			if ph, ok := byName[name]; !ok || ph.Secret {
+				log.Fatalf("Matrix variable '%s' is not a placeholder of '%s'", name, script.Name)
+			}
+			setBy[name]++
+		}
+	}
+	// Masked values can make labels alike, numbering tells them apart
+	labels := make(map[string]int)
+	for i, target := range targets {
+		targets[i].label = matrixLabel(executor.RedactInputs(placeholders, target.values))
+		labels[targets[i].label]++
+	}
+	for i, target := range targets {
+		if labels[target.label] > 1 {
+			targets[i].label += fmt.Sprintf(" #%d", i+1)
+		}
+	}
+
+	var shared []executor.Placeholder
+	for _, ph := range placeholders {
+		if setBy[ph.Name] < len(targets) {
+			shared = append(shared, ph)
+		} else if value, ok := inputs[ph.Name]; ok {
+			if _, err := ph.Resolve(value); err != nil {
+				log.Fatalf("Invalid placeholder value: %v", err)
+			}
+		}
+	}
+	if err := resolvePlaceholders(shared, inputs); err != nil {
+		log.Fatalf("Invalid placeholder value: %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	interpreter := executor.ResolveInterpreter(script.Content, script.Language, script.Interpreter, cfg.Interpreters)
+	quote := !script.RawPlaceholders && executor.IsShell(interpreter)
+
+	// Substitute and check every target before running any
+	targetInputs := make([]map[string]string, len(targets))
+	scripts := make([]string, len(targets))
+	dangerous := false
+	for i, target := range targets {
+		values := make(map[string]string, len(inputs)+len(target.values))
+		for name, value := range inputs {
+			values[name] = value
+		}
+		for name, value := range target.values {
+			resolved, err := byName[name].Resolve(value)
+			if err != nil {
+				log.Fatalf("Invalid value for %s: %v", target.label, err)
+			}
+			values[name] = resolved
+		}
+		targetInputs[i] = values
+		if scripts[i], err = executor.ReplacePlaceholders(script.Content, values, quote); err != nil {
+			log.Fatalf("Failed to substitute placeholders for %s: %v", target.label, err)
+		}
+
+		mask := executor.SensitiveValues(placeholders, values)
+		findings := executor.FilterAllowed(executor.Analyze(scripts[i]), script.AllowedChecks)
+		if runDryRun {
+			targetSources := make(map[string]string, len(sources))
+			for name, source := range sources {
+				targetSources[name] = source
+			}
+			for name := range target.values {
+				targetSources[name] = "--matrix"
+			}
+			fmt.Printf("=== %s ===\n", target.label)
+			printDryRun(script, interpreter, placeholders, values, targetSources, quote, findings)
+			fmt.Println()
+			continue
+		}
+		if len(findings) > 0 && !runYes {
+			fmt.Fprintf(os.Stderr, "'%s' looks dangerous for %s:\n", script.Name, target.label)
+			for _, finding := range findings {
+				fmt.Fprintln(os.Stderr, executor.MaskValues(finding.String(), mask))
+			}
+			dangerous = true
+		}
+	}
+	if runDryRun {
+		return
+	}
+	if dangerous {
+		fmt.Fprintf(os.Stderr, "Run it with --yes, or trust these checks with: bashhub allow %s <check>\n", script.Name)
+		os.Exit(1)
+	}
+
+	// Ctrl+C reaches the running targets through ForwardSignals; this only
+	// stops the ones still waiting from starting
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	color := term.IsTerminal(int(os.Stdout.Fd()))
+	var printMu sync.Mutex
+	results := make([]matrixResult, len(targets))
+	slots := make(chan struct{}, runConcurrency)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		// Targets start in order as slots free up
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].skipped = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, target matrixTarget) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			prefix := "[" + target.label + "] "
+			if color {
+				prefix = fmt.Sprintf("\033[%dm%s\033[0m", 31+i%6, prefix)
+			}
+			lines := &prefixedLines{prefix: prefix, mu: &printMu}
+
+			runID, recordErr := database.StartRun(db, script.ID, executor.RedactInputs(placeholders, targetInputs[i]))
+			if recordErr != nil {
+				lines.write(fmt.Sprintf("Failed to record run: %v\n", recordErr))
+			}
+
+			var output database.OutputTail
+			result, err := executor.Run(context.Background(), scripts[i], executor.Options{
+				Interpreter:    interpreter,
+				Name:           script.Name,
+				Timeout:        runTimeout,
+				ForwardSignals: true,
+				Mask:           executor.SensitiveValues(placeholders, targetInputs[i]),
+				Output: func(chunk string) {
+					output.Write(chunk)
+					lines.write(chunk)
+				},
+			})
+			lines.flush()
+			results[i] = matrixResult{result: result, err: err}
+
+			exitCode := result.ExitCode
+			if err != nil {
+				exitCode = database.ExitCodeError
+				output.Write(err.Error())
+			}
+			if recordErr == nil {
+				if err := database.FinishRun(db, runID, exitCode, output.String()); err != nil {
+					lines.write(fmt.Sprintf("Failed to record run: %v\n", err))
+				}
+			}
+		}(i, target)
+	}
+	wg.Wait()
+
+	failed := false
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tEXIT\tDURATION\tSTATUS")
+	for i, target := range targets {
+		r := results[i]
+		switch {
+		case r.skipped:
+			fmt.Fprintf(w, "%s\t-\t-\tskipped\n", target.label)
+		case r.err != nil:
+			fmt.Fprintf(w, "%s\t-\t-\tfailed to start: %v\n", target.label, r.err)
+		default:
+			status := "ok"
+			switch {
+			case r.result.TimedOut:
+				status = "timed out"
+			case r.result.Killed:
+				status = "cancelled"
+			case r.result.ExitCode != 0:
+				status = "failed"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", target.label, r.result.ExitCode, r.result.Duration.Round(time.Millisecond), status)
+		}
+		if r.skipped || r.err != nil || r.result.ExitCode != 0 {
+			failed = true
+		}
+	}
+	w.Flush()
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// prefixedLines prints output a whole line at a time with a prefix, so the
+// output of targets running at once doesn't mix within a line.
+type prefixedLines struct {
+	prefix  string
+	mu      *sync.Mutex
+	partial string
+}
+
+func (p *prefixedLines) write(chunk string) {
+	p.partial += chunk
+	end := strings.LastIndex(p.partial, "\n")
+	if end < 0 {
+		return
+	}
+	complete := p.partial[:end]
+	p.partial = p.partial[end+1:]
+
+	var b strings.Builder
+	for _, line := range strings.Split(complete, "\n") {
+		b.WriteString(p.prefix + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	p.mu.Lock()
+	fmt.Print(b.String())
+	p.mu.Unlock()
+}
+
+// flush prints a last line left without a newline.
+func (p *prefixedLines) flush() {
+	if p.partial != "" {
+		p.write("\n")
+	}
+}
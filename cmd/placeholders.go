@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/maccalsa/bashhub/internal/executor"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// placeholderEnvPrefix prefixes environment variables holding placeholder
+// values, e.g. BASHHUB_PH_DB_HOST for {{db-host}}.
+const placeholderEnvPrefix = "BASHHUB_PH_"
+
+var (
+	valuesFile     string
+	nonInteractive bool
+)
+
+// addPlaceholderFlags registers the placeholder value sources shared by the
+// commands that substitute placeholders.
+func addPlaceholderFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&valuesFile, "values-file", "", "Read placeholder values from a .yaml, .json or .env file")
+	cmd.Flags().BoolVar(&nonInteractive, "non-interactive", false, "Fail instead of prompting for missing placeholders (default when stdin is not a terminal)")
+}
+
+// collectPlaceholderValues gathers values from every source except the
+// prompt. Highest precedence first: --set, BASHHUB_PH_<NAME> variables, then
+// --values-file. Placeholder defaults apply only when no source has a value.
+// sources says where each value came from.
+func collectPlaceholderValues(placeholders []executor.Placeholder, set map[string]string) (inputs, sources map[string]string, err error) {
+	inputs = make(map[string]string)
+	sources = make(map[string]string)
+
+	if valuesFile != "" {
+		values, err := readValuesFile(valuesFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, value := range values {
+			inputs[name] = value
+			sources[name] = "--values-file"
+		}
+	}
+
+	for _, ph := range placeholders {
+		if ph.Secret {
+			continue
+		}
+		if value, ok := os.LookupEnv(placeholderEnvVar(ph.Name)); ok {
+			inputs[ph.Name] = value
+			sources[ph.Name] = "$" + placeholderEnvVar(ph.Name)
+		}
+	}
+
+	for name, value := range set {
+		inputs[name] = value
+		sources[name] = "--set"
+	}
+	// Secret values only ever come from the secrets store
+	return executor.WithoutSecrets(inputs), sources, nil
+}
+
+// placeholderEnvVar returns the environment variable for a placeholder:
+// upper-cased, with anything but letters and digits replaced by '_'.
+func placeholderEnvVar(name string) string {
+	return placeholderEnvPrefix + strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+// resolvePlaceholders validates the supplied values and prompts on stdin for
+// any placeholder that is still missing, re-asking until the input is valid.
+// In non-interactive mode missing placeholders take their default, and an
+// error lists every one without a default. Secret placeholders are never
+// asked for; left unresolved, they stay in the script as they are.
+func resolvePlaceholders(placeholders []executor.Placeholder, inputs map[string]string) error {
+	for _, ph := range placeholders {
+		value, ok := inputs[ph.Name]
+		if !ok {
+			continue
+		}
+		resolved, err := ph.Resolve(value)
+		if err != nil {
+			return err
+		}
+		inputs[ph.Name] = resolved
+	}
+
+	if nonInteractive || !term.IsTerminal(int(os.Stdin.Fd())) {
+		var missing []string
+		for _, ph := range placeholders {
+			if _, exists := inputs[ph.Name]; exists || ph.Secret {
+				continue
+			}
+			if !ph.HasDefault {
+				missing = append(missing, fmt.Sprintf("%s (%s)", ph.Name, placeholderEnvVar(ph.Name)))
+				continue
+			}
+			value, err := ph.Resolve("")
+			if err != nil {
+				return err
+			}
+			inputs[ph.Name] = value
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("missing values for placeholders: %s; supply them with --set, --values-file or the environment",
+				strings.Join(missing, ", "))
+		}
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+
+	for _, ph := range placeholders {
+		if _, exists := inputs[ph.Name]; exists || ph.Secret {
+			continue
+		}
+		for {
+			if hint := ph.Describe(); hint != "" {
+				fmt.Printf("Enter value for '%s' (%s): ", ph.Name, hint)
+			} else {
+				fmt.Printf("Enter value for '%s': ", ph.Name)
+			}
+
+			var text string
+			var readErr error
+			if ph.Sensitive {
+				var secret []byte
+				secret, readErr = term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				text = string(secret)
+			} else {
+				text, readErr = reader.ReadString('\n')
+			}
+			value, err := ph.Resolve(strings.TrimSpace(text))
+			if err == nil {
+				inputs[ph.Name] = value
+				break
+			}
+			if readErr != nil {
+				return err
+			}
+			fmt.Printf("Invalid value: %v\n", err)
+		}
+	}
+
+	return nil
+}
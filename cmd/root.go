@@ -3,26 +3,59 @@ package cmd
 import (
 	"log"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/config"
 	"github.com/maccalsa/bashhub/internal/database"
 	"github.com/maccalsa/bashhub/internal/tui"
 	"github.com/spf13/cobra"
 )
 
+var (
+	dbFlag      string
+	profileFlag string
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "bashhub",
 	Short: "BashHub is a dynamic script execution manager",
 	Run: func(cmd *cobra.Command, args []string) {
 		// This is the default command (launches the TUI)
-		db := database.ConnectDB()
-		ui := tui.NewUI(db)
+		db := connectDB()
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		ui := tui.NewUI(db, cfg)
 		if err := ui.Run(); err != nil {
 			log.Fatalf("Failed to run UI: %v", err)
 		}
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&dbFlag, "db", "", "Path to the database file (overrides $"+config.EnvDB+")")
+	rootCmd.PersistentFlags().StringVarP(&profileFlag, "profile", "p", "", "Use the database of a named profile (overrides $"+config.EnvProfile+")")
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// dbPath resolves the database selected by --db, --profile or the environment.
+func dbPath() string {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	path, err := cfg.DBPath(dbFlag, profileFlag)
+	if err != nil {
+		log.Fatalf("Failed to locate database: %v", err)
+	}
+	return path
+}
+
+func connectDB() *sqlx.DB {
+	return database.ConnectDB(dbPath())
+}
@@ -1,26 +1,44 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/maccalsa/bashhub/internal/config"
 	"github.com/maccalsa/bashhub/internal/database"
 	"github.com/maccalsa/bashhub/internal/executor"
+	"github.com/maccalsa/bashhub/internal/secrets"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-var placeholderInputs []string
+var (
+	placeholderInputs []string
+	runTimeout        time.Duration
+	runYes            bool
+	runDryRun         bool
+	runMatrixValues   []string
+	runMatrixFile     string
+	runConcurrency    int
+)
 
 var runCmd = &cobra.Command{
 	Use:   "run [script-name]",
 	Short: "Run a bash script by name",
-	Args:  cobra.ExactArgs(1),
+	Long: `Run a bash script by name.
+
+With --matrix or --matrix-file the script runs once per combination of
+values, up to --concurrency at a time. Each target runs without a terminal
+or stdin, its output lines prefixed with its values, and a table of exit
+codes follows. bashhub then exits with 1 if any target failed.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		scriptName := args[0]
-		db := database.ConnectDB()
+		db := connectDB()
 
 		scripts, err := database.GetScripts(db)
 		if err != nil {
@@ -39,33 +57,156 @@ var runCmd = &cobra.Command{
 			log.Fatalf("Script '%s' not found", scriptName)
 		}
 
-		// Parse placeholder values provided via --set flags
-		inputs := parsePlaceholderInputs(placeholderInputs)
+		placeholders, err := executor.ParsePlaceholders(selectedScript.Content)
+		if err != nil {
+			log.Fatalf("Invalid placeholder: %v", err)
+		}
 
-		placeholders := executor.ParsePlaceholders(selectedScript.Content)
+		// Parse placeholder values provided via --set flags and other sources
+		inputs, sources, err := collectPlaceholderValues(placeholders, parsePlaceholderInputs(placeholderInputs))
+		if err != nil {
+			log.Fatalf("Failed to read placeholder values: %v", err)
+		}
 
-		reader := bufio.NewReader(os.Stdin)
+		if secrets.NeedsSecrets(placeholders) {
+			if err := unlockSecrets(db).Resolve(placeholders, inputs); err != nil {
+				log.Fatalf("Failed to read secrets: %v", err)
+			}
+		}
 
-		for _, ph := range placeholders {
-			if _, ok := inputs[ph]; !ok {
-				fmt.Printf("%s: ", ph)
-				text, _ := reader.ReadString('\n')
-				inputs[ph] = strings.TrimSpace(text)
+		targets, err := loadMatrix(runMatrixValues, runMatrixFile)
+		if err != nil {
+			log.Fatalf("Invalid matrix: %v", err)
+		}
+		if targets != nil {
+			runMatrix(db, *selectedScript, placeholders, inputs, sources, targets)
+			return
+		}
+
+		if err := resolvePlaceholders(placeholders, inputs); err != nil {
+			log.Fatalf("Invalid placeholder value: %v", err)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		interpreter := executor.ResolveInterpreter(selectedScript.Content, selectedScript.Language, selectedScript.Interpreter, cfg.Interpreters)
+
+		quote := !selectedScript.RawPlaceholders && executor.IsShell(interpreter)
+		finalScript, err := executor.ReplacePlaceholders(selectedScript.Content, inputs, quote)
+		if err != nil {
+			log.Fatalf("Failed to substitute placeholders: %v", err)
+		}
+		mask := executor.SensitiveValues(placeholders, inputs)
+
+		findings := executor.FilterAllowed(executor.Analyze(finalScript), selectedScript.AllowedChecks)
+		if runDryRun {
+			printDryRun(*selectedScript, interpreter, placeholders, inputs, sources, quote, findings)
+			return
+		}
+		if len(findings) > 0 && !runYes {
+			fmt.Fprintf(os.Stderr, "'%s' looks dangerous:\n", selectedScript.Name)
+			for _, finding := range findings {
+				fmt.Fprintln(os.Stderr, executor.MaskValues(finding.String(), mask))
 			}
+			fmt.Fprintf(os.Stderr, "Run it with --yes, or trust these checks with: bashhub allow %s <check>\n", selectedScript.Name)
+			os.Exit(1)
 		}
 
-		finalScript := executor.ReplacePlaceholders(selectedScript.Content, inputs)
+		runID, recordErr := database.StartRun(db, selectedScript.ID, executor.RedactInputs(placeholders, inputs))
+		if recordErr != nil {
+			log.Printf("Failed to record run: %v", recordErr)
+		}
 
-		err = executor.ExecuteScript(finalScript, func(line string) {
-			fmt.Println(line)
+		var output database.OutputTail
+		result, err := executor.Run(context.Background(), finalScript, executor.Options{
+			Interpreter:    interpreter,
+			Name:           selectedScript.Name,
+			Timeout:        runTimeout,
+			Stdin:          os.Stdin,
+			PTY:            true,
+			ForwardSignals: true,
+			Mask:           mask,
+			Output: func(chunk string) {
+				output.Write(chunk)
+				fmt.Print(chunk)
+			},
 		})
-
+		exitCode := result.ExitCode
+		if err != nil {
+			exitCode = database.ExitCodeError
+			output.Write(err.Error())
+		}
+		if recordErr == nil {
+			if err := database.FinishRun(db, runID, exitCode, output.String()); err != nil {
+				log.Printf("Failed to record run: %v", err)
+			}
+		}
 		if err != nil {
 			log.Fatalf("Script execution failed: %v", err)
 		}
+
+		// Exit with the script's own status so bashhub can be used as a
+		// step in Makefiles and CI pipelines. Timeouts follow timeout(1).
+		if result.TimedOut {
+			fmt.Fprintf(os.Stderr, "Script timed out after %s\n", runTimeout)
+			os.Exit(124)
+		}
+		os.Exit(result.ExitCode)
 	},
 }
 
+// printDryRun shows what run would execute, with sensitive values masked.
+func printDryRun(script database.Script, interpreter []string, placeholders []executor.Placeholder,
+	inputs, sources map[string]string, quote bool, findings []executor.Finding) {
+	mask := executor.SensitiveValues(placeholders, inputs)
+	masked := executor.MaskInputs(placeholders, inputs)
+
+	fmt.Printf("Script:      %s\n", script.Name)
+	fmt.Printf("Interpreter: %s\n", strings.Join(interpreter, " "))
+	if dir, err := os.Getwd(); err == nil {
+		fmt.Printf("Directory:   %s\n", dir)
+	}
+	if runTimeout > 0 {
+		fmt.Printf("Timeout:     %s\n", runTimeout)
+	}
+	fmt.Printf("Quoting:     %t\n", quote)
+
+	if len(placeholders) > 0 {
+		fmt.Println("\nPlaceholders:")
+		for _, ph := range placeholders {
+			source := sources[ph.Name]
+			switch {
+			case ph.Secret:
+				source = "secrets"
+			case source == "" && (nonInteractive || !term.IsTerminal(int(os.Stdin.Fd()))):
+				source = "default"
+			case source == "":
+				source = "prompt"
+			}
+			fmt.Printf("  %-20s %-30s %s\n", ph.Name, masked[ph.Name], source)
+		}
+	}
+
+	if len(findings) > 0 {
+		fmt.Println("\nDangerous commands (run needs --yes):")
+		for _, finding := range findings {
+			fmt.Println(executor.MaskValues(finding.String(), mask))
+		}
+	}
+
+	fmt.Println("\n---")
+	shown, err := executor.ReplacePlaceholders(script.Content, masked, quote)
+	if err != nil {
+		log.Fatalf("Failed to substitute placeholders: %v", err)
+	}
+	fmt.Print(shown)
+	if !strings.HasSuffix(shown, "\n") {
+		fmt.Println()
+	}
+}
+
 func parsePlaceholderInputs(inputPairs []string) map[string]string {
 	inputs := make(map[string]string)
 	for _, pair := range inputPairs {
@@ -79,5 +220,12 @@ func parsePlaceholderInputs(inputPairs []string) map[string]string {
 
 func init() {
 	runCmd.Flags().StringArrayVarP(&placeholderInputs, "set", "s", []string{}, "Set placeholder values (key=value)")
+	addPlaceholderFlags(runCmd)
+	runCmd.Flags().BoolVarP(&runDryRun, "dry-run", "n", false, "Print the resolved script, interpreter and values without running it")
+	runCmd.Flags().BoolVarP(&runYes, "yes", "y", false, "Run even if the script contains dangerous commands")
+	runCmd.Flags().StringArrayVar(&runMatrixValues, "matrix", nil, "Run once per value of a placeholder, in parallel (name=a,b,c; repeatable, combined)")
+	runCmd.Flags().StringVar(&runMatrixFile, "matrix-file", "", "Read matrix values from a .yaml or .json file")
+	runCmd.Flags().IntVarP(&runConcurrency, "concurrency", "j", 4, "Maximum number of matrix targets running at once")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 0, "Stop the script after this long (e.g. 30s, 5m); exits with 124")
 	rootCmd.AddCommand(runCmd)
 }
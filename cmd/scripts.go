@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
+	"github.com/maccalsa/bashhub/internal/tui"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	addCategory        string
+	addDescription     string
+	addFromFile        string
+	addInterpreter     string
+	addRawPlaceholders bool
+	addAllow           []string
+	addTags            []string
+	rmForce            bool
+	mvCategory         string
+	mvTree             bool
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add <script-name>",
+	Short: "Add a script from a file, stdin or $EDITOR",
+	Long: `Add a script to the library.
+
+The content is read from --from-file, from stdin when it is not a terminal,
+or otherwise written in $EDITOR.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if err := database.ValidateName(name); err != nil {
+			log.Fatalf("Invalid name: %v", err)
+		}
+		allowed, err := executor.ParseAllowlist(strings.Join(addAllow, " "))
+		if err != nil {
+			log.Fatalf("Invalid --allow: %v", err)
+		}
+		tags, err := database.NormalizeTags(addTags)
+		if err != nil {
+			log.Fatalf("Invalid --tag: %v", err)
+		}
+		category := database.CleanCategory(addCategory)
+		if category == "" {
+			log.Fatal("Category cannot be empty")
+		}
+
+		var content []byte
+		switch {
+		case addFromFile == "-" || (addFromFile == "" && !term.IsTerminal(int(os.Stdin.Fd()))):
+			content, err = io.ReadAll(os.Stdin)
+		case addFromFile != "":
+			content, err = os.ReadFile(addFromFile)
+		default:
+			var edited string
+			edited, err = tui.EditContent("")
+			content = []byte(edited)
+		}
+		if err != nil {
+			log.Fatalf("Failed to read script content: %v", err)
+		}
+		if strings.TrimSpace(string(content)) == "" {
+			log.Fatalf("Script content cannot be empty")
+		}
+
+		script := database.Script{
+			Name:            name,
+			Description:     addDescription,
+			Content:         string(content),
+			Category:        category,
+			Language:        tui.DetectLanguageForFile(addFromFile, string(content)),
+			RawPlaceholders: addRawPlaceholders,
+			Interpreter:     addInterpreter,
+			AllowedChecks:   allowed,
+			Tags:            tags,
+		}
+
+		db := connectDB()
+		if err := database.CreateScript(db, script); err != nil {
+			log.Fatalf("Failed to add script: %v", nameError(err, name))
+		}
+		fmt.Printf("Added '%s' (%s) to %s\n", name, script.Language, script.Category)
+	},
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit <script-name>",
+	Short: "Edit a script's content in $EDITOR",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script := mustGetScript(db, args[0])
+
+		content, err := tui.EditContent(script.Content)
+		if err != nil {
+			log.Fatalf("Editor error: %v", err)
+		}
+		if strings.TrimSpace(content) == "" {
+			log.Fatalf("Script content cannot be empty, keeping the previous version")
+		}
+		if content == script.Content {
+			fmt.Println("No changes.")
+			return
+		}
+
+		script.Content = content
+		script.Language = tui.DetectLanguage(content)
+		if err := database.UpdateScript(db, script); err != nil {
+			log.Fatalf("Failed to update script: %v", err)
+		}
+		fmt.Printf("Updated '%s'\n", script.Name)
+	},
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <script-name>",
+	Short: "Delete a script with its run and version history",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script := mustGetScript(db, args[0])
+
+		if !rmForce {
+			if !term.IsTerminal(int(os.Stdin.Fd())) {
+				log.Fatalf("Refusing to delete '%s' without confirmation; use --force", script.Name)
+			}
+			fmt.Printf("Delete '%s' and its history? [y/N] ", script.Name)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+				fmt.Println("Cancelled.")
+				return
+			}
+		}
+
+		if err := database.DeleteScript(db, script.ID); err != nil {
+			log.Fatalf("Failed to delete script: %v", err)
+		}
+		fmt.Printf("Deleted '%s'\n", script.Name)
+	},
+}
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <script-name> [new-name] [--category path]",
+	Short: "Rename a script or move it to another category",
+	Long: `Rename a script or move it to another category.
+
+Categories nest with slashes, as in AWS/EC2. With --tree, the argument is a
+category instead of a script: it moves with all its subcategories under
+--category, so "bashhub mv --tree AWS --category Cloud/AWS" turns AWS/EC2
+into Cloud/AWS/EC2.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if mvTree {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.RangeArgs(1, 2)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		moving := cmd.Flags().Changed("category")
+		category := database.CleanCategory(mvCategory)
+		if moving && category == "" {
+			log.Fatal("Category cannot be empty")
+		}
+
+		db := connectDB()
+		if mvTree {
+			if !moving {
+				log.Fatal("--tree needs --category to move to")
+			}
+			moved, err := database.MoveCategory(db, args[0], category)
+			if err != nil {
+				log.Fatalf("Failed to move category: %v", err)
+			}
+			if moved == 0 {
+				log.Fatalf("No scripts in category '%s'", args[0])
+			}
+			fmt.Printf("Moved %d scripts from %s to %s\n", moved, database.CleanCategory(args[0]), category)
+			return
+		}
+
+		if len(args) == 1 && !moving {
+			log.Fatal("Give a new name, --category or both")
+		}
+		script := mustGetScript(db, args[0])
+		if len(args) == 2 {
+			script.Name = args[1]
+		}
+		if moving {
+			script.Category = category
+		}
+		if err := database.UpdateScript(db, script); err != nil {
+			log.Fatalf("Failed to update script: %v", nameError(err, script.Name))
+		}
+
+		if len(args) == 2 {
+			fmt.Printf("Renamed '%s' to '%s'\n", args[0], args[1])
+		}
+		if moving {
+			fmt.Printf("Moved '%s' to %s\n", script.Name, category)
+		}
+	},
+}
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <script-name> <new-name>",
+	Short: "Duplicate a script under a new name",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script := mustGetScript(db, args[0])
+
+		if _, err := database.CopyScript(db, script, args[1]); err != nil {
+			log.Fatalf("Failed to copy script: %v", nameError(err, args[1]))
+		}
+		fmt.Printf("Copied '%s' to '%s'\n", args[0], args[1])
+	},
+}
+
+func init() {
+	addCmd.Flags().StringVarP(&addCategory, "category", "c", "General", "Category of the script")
+	addCmd.Flags().StringVarP(&addDescription, "description", "d", "", "Description of the script")
+	addCmd.Flags().StringVarP(&addFromFile, "from-file", "f", "", "Read the content from this file ('-' for stdin)")
+	addCmd.Flags().StringVar(&addInterpreter, "interpreter", "", "Command to run the script with, overriding shebang and language")
+	addCmd.Flags().BoolVar(&addRawPlaceholders, "raw-placeholders", false, "Substitute placeholder values without shell quoting")
+	addCmd.Flags().StringSliceVarP(&addTags, "tag", "t", nil, "Tag the script (repeatable or comma separated)")
+	addCmd.Flags().StringSliceVar(&addAllow, "allow", nil, "Dangerous-command checks not to warn about (see bashhub allow)")
+	mvCmd.Flags().StringVarP(&mvCategory, "category", "c", "", "Move the script, or the category with --tree, to this category")
+	mvCmd.Flags().BoolVar(&mvTree, "tree", false, "Move a category and its subcategories instead of a script")
+	rmCmd.Flags().BoolVarP(&rmForce, "force", "f", false, "Delete without asking for confirmation")
+	rootCmd.AddCommand(addCmd, editCmd, rmCmd, mvCmd, cpCmd)
+}
+
+// nameError names the script in a duplicate name error
+func nameError(err error, name string) error {
+	if errors.Is(err, database.ErrScriptExists) {
+		return fmt.Errorf("a script named '%s' already exists", name)
+	}
+	return err
+}
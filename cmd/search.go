@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	searchLimit int
+	searchTags  []string
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search scripts by name, description and content",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tags, err := database.NormalizeTags(searchTags)
+		if err != nil {
+			log.Fatalf("Invalid --tag: %v", err)
+		}
+
+		db := connectDB()
+
+		// Tags are filtered after ranking, so the limit is applied here
+		limit := searchLimit
+		if len(tags) > 0 {
+			limit = 0
+		}
+		results, err := database.SearchScripts(db, strings.Join(args, " "), limit)
+		if err != nil {
+			log.Fatalf("Search failed: %v", err)
+		}
+		if len(tags) > 0 {
+			var tagged []database.SearchResult
+			for _, result := range results {
+				if result.HasTags(tags) && (searchLimit <= 0 || len(tagged) < searchLimit) {
+					tagged = append(tagged, result)
+				}
+			}
+			results = tagged
+		}
+		if len(results) == 0 {
+			fmt.Println("No matching scripts.")
+			return
+		}
+
+		// Emphasise matches on a terminal, bracket them when piped
+		start, end := "[", "]"
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			start, end = "\033[1;33m", "\033[0m"
+		}
+		highlight := strings.NewReplacer(database.MatchStart, start, database.MatchEnd, end)
+
+		for i, result := range results {
+			fmt.Printf("%d. %s [%s]", i+1, result.Name, result.Category)
+			if result.Description != "" {
+				fmt.Printf(" - %s", result.Description)
+			}
+			fmt.Println()
+			if result.Snippet != "" {
+				snippet := strings.Join(strings.Fields(result.Snippet), " ")
+				fmt.Printf("   %s\n", highlight.Replace(snippet))
+			}
+		}
+	},
+}
+
+func init() {
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "Maximum number of results (0 for all)")
+	searchCmd.Flags().StringSliceVarP(&searchTags, "tag", "t", nil, "Only show scripts with this tag (repeatable, all must match)")
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/config"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/secrets"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var secretListJSON bool
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage encrypted secrets used by {{secret:name}} placeholders",
+	Long: `Manage encrypted secrets used by {{secret:name}} placeholders.
+
+Secret values are encrypted in the database with a key derived from a
+passphrase, or from the contents of a key file. The key comes from
+$BASHHUB_SECRETS_KEY_FILE, secrets_key_file in config.json or
+$BASHHUB_SECRETS_PASSPHRASE, otherwise bashhub asks for the passphrase. The
+first passphrase used becomes the key for the database.
+
+Secrets are filled in when a script runs. They are never recorded in the
+run history, and export leaves {{secret:name}} in place.`,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name> [value]",
+	Short: "Store a secret, read from a prompt or stdin unless given",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := secrets.ValidateName(args[0]); err != nil {
+			log.Fatal(err)
+		}
+		db := connectDB()
+		store := unlockSecrets(db)
+
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			var err error
+			if value, err = readSecretValue(fmt.Sprintf("Value for '%s': ", args[0])); err != nil {
+				log.Fatalf("Failed to read value: %v", err)
+			}
+		}
+
+		if err := store.Set(args[0], value); err != nil {
+			log.Fatalf("Failed to store secret: %v", err)
+		}
+		fmt.Printf("Secret '%s' saved\n", args[0])
+	},
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print a secret's value",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		value, err := unlockSecrets(db).Get(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(value)
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List secret names, without their values",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		list, err := database.GetSecrets(db)
+		if err != nil {
+			log.Fatalf("Failed to load secrets: %v", err)
+		}
+
+		if secretListJSON {
+			if list == nil {
+				list = []database.Secret{}
+			}
+			printJSON(list)
+			return
+		}
+		for _, secret := range list {
+			fmt.Printf("%-30s updated %s\n", secret.Name, secret.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+	},
+}
+
+var secretRmCmd = &cobra.Command{
+	Use:   "rm <name>",
+	Short: "Delete a secret",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		err := database.DeleteSecret(db, args[0])
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Fatalf("Secret '%s' not found", args[0])
+		} else if err != nil {
+			log.Fatalf("Failed to delete secret: %v", err)
+		}
+		fmt.Printf("Secret '%s' deleted\n", args[0])
+	},
+}
+
+var secretKeygenCmd = &cobra.Command{
+	Use:   "keygen <path>",
+	Short: "Write a random key file to use instead of a passphrase",
+	Long: `Write a random key file to use instead of a passphrase.
+
+Point $BASHHUB_SECRETS_KEY_FILE or secrets_key_file in config.json at it
+before storing the first secret; a database keeps the key it was first
+unlocked with.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			log.Fatalf("Failed to generate key: %v", err)
+		}
+		file, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			log.Fatalf("Failed to create key file: %v", err)
+		}
+		defer file.Close()
+		if _, err := fmt.Fprintln(file, key); err != nil {
+			log.Fatalf("Failed to write key file: %v", err)
+		}
+		fmt.Println("Key written to", args[0])
+	},
+}
+
+func init() {
+	secretListCmd.Flags().BoolVar(&secretListJSON, "json", false, "Print the list as JSON")
+	secretCmd.AddCommand(secretSetCmd, secretGetCmd, secretListCmd, secretRmCmd, secretKeygenCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+// unlockSecrets unlocks the secrets with the configured key, or a passphrase
+// asked for on the terminal, exiting on failure.
+func unlockSecrets(db *sqlx.DB) *secrets.Store {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	key, err := secrets.KeyFromEnv(cfg.SecretsKeyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if key == nil {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			log.Fatal(secrets.ErrNoKey)
+		}
+		initialized, err := secrets.Initialized(db)
+		if err != nil {
+			log.Fatalf("Failed to read secrets settings: %v", err)
+		}
+
+		if key, err = readPassword("Secrets passphrase: "); err != nil {
+			log.Fatalf("Failed to read passphrase: %v", err)
+		}
+		if !initialized && len(key) > 0 {
+			confirm, err := readPassword("Repeat the new passphrase: ")
+			if err != nil {
+				log.Fatalf("Failed to read passphrase: %v", err)
+			}
+			if string(confirm) != string(key) {
+				log.Fatal("Passphrases don't match")
+			}
+		}
+	}
+
+	store, err := secrets.Unlock(db, key)
+	if err != nil {
+		log.Fatalf("Failed to unlock secrets: %v", err)
+	}
+	return store
+}
+
+// readPassword reads a line from the terminal without echoing it.
+func readPassword(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+	return term.ReadPassword(int(os.Stdin.Fd()))
+}
+
+// readSecretValue asks for a value without echo on a terminal, or reads all
+// of stdin, minus a trailing newline, when it is piped in.
+func readSecretValue(prompt string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		value, err := readPassword(prompt)
+		return string(value), err
+	}
+	data, err := io.ReadAll(os.Stdin)
+	return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), err
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/maccalsa/bashhub/internal/gitsync"
+	"github.com/maccalsa/bashhub/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncRemote string
+	syncMine   bool
+	syncTheirs bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Share the library through a git repository",
+	Long: `Share the library through a git repository.
+
+The library is mirrored to a git working tree, one file per script laid out
+like export-all. push commits changes and pushes them to the repository's
+origin remote, pull merges the remote's changes into the library. A script
+changed on both sides is left as a conflict to resolve in the TUI (S) or
+with "bashhub sync resolve".`,
+}
+
+var syncInitCmd = &cobra.Command{
+	Use:   "init <path>",
+	Short: "Bind the library to a git working tree",
+	Long: `Bind the library to a git working tree.
+
+An existing repository at path is used as is. Otherwise, with --remote and
+an empty or missing path, the remote is cloned; without it a new repository
+is created there. Scripts already in the repository are read into the
+library.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		repo, report, err := gitsync.Init(db, args[0], syncRemote, tui.DetectLanguageForFile)
+		if err != nil {
+			log.Fatalf("Failed to set up sync: %v", err)
+		}
+		printSyncReport(report)
+
+		fmt.Printf("Library synced with %s", repo.Path)
+		if remote := repo.Remote(); remote != "" {
+			fmt.Printf(" (remote %s)", remote)
+		}
+		fmt.Println()
+	},
+}
+
+var syncPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Commit the library and push it to the remote",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := mustOpenSync()
+		report, err := repo.Push()
+		printSyncReport(report)
+		if err != nil {
+			log.Fatalf("Push failed: %v", err)
+		}
+
+		switch {
+		case report.Pushed:
+			fmt.Println("Pushed to", repo.Remote())
+		case report.Committed:
+			fmt.Println("Committed; no remote configured")
+		default:
+			fmt.Println("Nothing to push")
+		}
+	},
+}
+
+var syncPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Merge changes from the remote into the library",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := mustOpenSync()
+		report, err := repo.Pull()
+		printSyncReport(report)
+		if err != nil {
+			log.Fatalf("Pull failed: %v", err)
+		}
+		if len(report.Added)+len(report.Updated)+len(report.Deleted) == 0 {
+			fmt.Println("Already up to date")
+		}
+	},
+}
+
+var syncStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the sync repository and any conflicts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := mustOpenSync()
+		fmt.Println("Path:  ", repo.Path)
+		remote := repo.Remote()
+		if remote == "" {
+			remote = "(none)"
+		}
+		fmt.Println("Remote:", remote)
+
+		conflicts, err := repo.Conflicts()
+		if err != nil {
+			log.Fatalf("Failed to read conflicts: %v", err)
+		}
+		printSyncReport(gitsync.Report{Conflicts: conflictPaths(conflicts)})
+	},
+}
+
+var syncResolveCmd = &cobra.Command{
+	Use:   "resolve <path> (--mine | --theirs)",
+	Short: "Resolve a conflict by keeping one side",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if syncMine == syncTheirs {
+			log.Fatalf("Use exactly one of --mine and --theirs")
+		}
+
+		repo := mustOpenSync()
+		report, err := repo.Resolve(args[0], syncMine)
+		printSyncReport(report)
+		if err != nil {
+			log.Fatalf("Failed to resolve: %v", err)
+		}
+		if len(report.Conflicts) == 0 {
+			fmt.Println("All conflicts resolved and merged")
+		}
+	},
+}
+
+func init() {
+	syncInitCmd.Flags().StringVar(&syncRemote, "remote", "", "URL or path of the remote repository (set as origin)")
+	syncResolveCmd.Flags().BoolVar(&syncMine, "mine", false, "Keep the library's version")
+	syncResolveCmd.Flags().BoolVar(&syncTheirs, "theirs", false, "Take the remote's version")
+	syncCmd.AddCommand(syncInitCmd, syncPushCmd, syncPullCmd, syncStatusCmd, syncResolveCmd)
+	rootCmd.AddCommand(syncCmd)
+}
+
+func mustOpenSync() *gitsync.Repo {
+	repo, err := gitsync.Open(connectDB(), tui.DetectLanguageForFile)
+	if errors.Is(err, gitsync.ErrNotConfigured) {
+		log.Fatal(err)
+	} else if err != nil {
+		log.Fatalf("Failed to open sync repository: %v", err)
+	}
+	return repo
+}
+
+func printSyncReport(report gitsync.Report) {
+	for _, group := range []struct {
+		label string
+		items []string
+	}{
+		{"added", report.Added},
+		{"updated", report.Updated},
+		{"deleted", report.Deleted},
+		{"error", report.Errors},
+		{"skipped", report.Skipped},
+	} {
+		for _, item := range group.items {
+			fmt.Printf("%-10s %s\n", group.label, item)
+		}
+	}
+	if len(report.Conflicts) > 0 {
+		fmt.Printf("%d conflicts, resolve them in the TUI (S) or with 'bashhub sync resolve <path> --mine|--theirs':\n  %s\n",
+			len(report.Conflicts), strings.Join(report.Conflicts, "\n  "))
+	}
+}
+
+func conflictPaths(conflicts []gitsync.Conflict) []string {
+	paths := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		paths[i] = c.Path
+	}
+	return paths
+}
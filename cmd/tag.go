@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagRemove    bool
+	tagsListJSON bool
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag <script-name> [tag...]",
+	Short: "Add tags to a script, or remove them with --remove",
+	Long: `Add tags to a script, or remove them with --remove.
+
+Tags are lowercase words without spaces or commas. A script can have any
+number of them, unlike its single category. Without tags, the script's
+tags are printed.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script, err := database.GetScriptByName(db, args[0])
+		if err != nil {
+			log.Fatalf("Script '%s' not found", args[0])
+		}
+
+		if len(args) == 1 {
+			for _, tag := range script.Tags {
+				fmt.Println(tag)
+			}
+			return
+		}
+
+		tags, err := database.ParseTags(strings.Join(args[1:], " "))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if tagRemove {
+			remove := make(map[string]bool)
+			for _, tag := range tags {
+				remove[tag] = true
+			}
+			var kept []string
+			for _, tag := range script.Tags {
+				if !remove[tag] {
+					kept = append(kept, tag)
+				}
+			}
+			tags = kept
+		} else {
+			tags = append(tags, script.Tags...)
+		}
+
+		if err := database.SetScriptTags(db, script.ID, tags); err != nil {
+			log.Fatalf("Failed to update tags: %v", err)
+		}
+		script, err = database.GetScriptByID(db, script.ID)
+		if err != nil {
+			log.Fatalf("Failed to reload script: %v", err)
+		}
+		if len(script.Tags) == 0 {
+			fmt.Printf("'%s' has no tags\n", script.Name)
+		} else {
+			fmt.Printf("Tags of '%s': %s\n", script.Name, strings.Join(script.Tags, ", "))
+		}
+	},
+}
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List tags with the number of scripts carrying each",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		tags, err := database.GetTags(db)
+		if err != nil {
+			log.Fatalf("Failed to load tags: %v", err)
+		}
+
+		if tagsListJSON {
+			if tags == nil {
+				tags = []database.Tag{}
+			}
+			printJSON(tags)
+			return
+		}
+		if len(tags) == 0 {
+			fmt.Println("No tags.")
+			return
+		}
+		for _, tag := range tags {
+			fmt.Printf("%-30s %d\n", tag.Name, tag.Scripts)
+		}
+	},
+}
+
+func init() {
+	tagCmd.Flags().BoolVar(&tagRemove, "remove", false, "Remove the given tags instead of adding them")
+	tagsCmd.Flags().BoolVar(&tagsListJSON, "json", false, "Print the list as JSON")
+	rootCmd.AddCommand(tagCmd, tagsCmd)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readValuesFile loads placeholder values from a flat YAML or JSON mapping,
+// or from a .env file of KEY=VALUE lines. The format follows the extension.
+func readValuesFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		var raw map[string]interface{}
+		if err = yaml.Unmarshal(data, &raw); err == nil {
+			values, err = scalarValues(raw)
+		}
+	case ".json":
+		var raw map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err = decoder.Decode(&raw); err == nil {
+			values, err = scalarValues(raw)
+		}
+	case ".env":
+		values, err = parseDotEnv(data)
+	default:
+		return nil, fmt.Errorf("%s: unsupported values file type '%s', use .yaml, .json or .env", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// scalarValues converts decoded YAML or JSON values to their string form.
+func scalarValues(raw map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			values[name] = ""
+		case string:
+			values[name] = v
+		case bool, int, int64, uint64, float64, json.Number:
+			values[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("value of '%s' must be a string, number or boolean", name)
+		}
+	}
+	return values, nil
+}
+
+// parseDotEnv reads KEY=VALUE lines. Blank lines, # comments and a leading
+// "export" are ignored; values may be single or double quoted.
+func parseDotEnv(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			// an unquoted value ends at an inline comment
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		values[name] = value
+	}
+	return values, scanner.Err()
+}
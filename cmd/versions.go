@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/diff"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions <script-name>",
+	Short: "List the saved versions of a script",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script := mustGetScript(db, args[0])
+
+		versions, err := database.GetScriptVersions(db, script.ID)
+		if err != nil {
+			log.Fatalf("Failed to load versions: %v", err)
+		}
+		if len(versions) == 0 {
+			fmt.Println("No versions recorded yet.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tDATE\tAUTHOR\tLINES\tDESCRIPTION")
+		for _, v := range versions {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n",
+				v.Version, v.CreatedAt.Local().Format("2006-01-02 15:04:05"), v.Author,
+				strings.Count(strings.TrimSuffix(v.Content, "\n"), "\n")+1, v.Description)
+		}
+		w.Flush()
+	},
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <script-name> [v1] [v2]",
+	Short: "Show a unified diff between versions of a script",
+	Long: `Show a unified diff between versions of a script.
+
+With no versions, shows the latest change. With one version, compares it
+to the current content. With two versions, compares them.`,
+	Args: cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script := mustGetScript(db, args[0])
+
+		var from, to database.ScriptVersion
+		switch len(args) {
+		case 1:
+			versions, err := database.GetScriptVersions(db, script.ID)
+			if err != nil {
+				log.Fatalf("Failed to load versions: %v", err)
+			}
+			if len(versions) < 2 {
+				fmt.Println("No earlier version to compare with.")
+				return
+			}
+			from, to = versions[len(versions)-2], versions[len(versions)-1]
+		case 2:
+			from = mustGetVersion(db, script, args[1])
+			to = database.ScriptVersion{Content: script.Content}
+		case 3:
+			from = mustGetVersion(db, script, args[1])
+			to = mustGetVersion(db, script, args[2])
+		}
+
+		fromLabel := fmt.Sprintf("%s v%d", script.Name, from.Version)
+		toLabel := fmt.Sprintf("%s (current)", script.Name)
+		if to.Version > 0 {
+			toLabel = fmt.Sprintf("%s v%d", script.Name, to.Version)
+		}
+
+		printDiff(diff.Unified(from.Content, to.Content, fromLabel, toLabel, 3))
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <script-name> <version>",
+	Short: "Restore a script to an earlier version",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := connectDB()
+		script := mustGetScript(db, args[0])
+		version := mustGetVersion(db, script, args[1])
+
+		if _, err := database.RollbackScript(db, script, version.Version); err != nil {
+			log.Fatalf("Failed to roll back: %v", err)
+		}
+		fmt.Printf("Rolled back '%s' to version %d.\n", script.Name, version.Version)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionsCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+func mustGetScript(db *sqlx.DB, name string) database.Script {
+	script, err := database.GetScriptByName(db, name)
+	if err != nil {
+		log.Fatalf("Script '%s' not found", name)
+	}
+	return script
+}
+
+func mustGetVersion(db *sqlx.DB, script database.Script, arg string) database.ScriptVersion {
+	number, err := strconv.Atoi(strings.TrimPrefix(arg, "v"))
+	if err != nil {
+		log.Fatalf("Invalid version '%s'", arg)
+	}
+	version, err := database.GetScriptVersion(db, script.ID, number)
+	if err != nil {
+		log.Fatalf("Version %d of '%s' not found", number, script.Name)
+	}
+	return version
+}
+
+// printDiff writes a unified diff, coloured when stdout is a terminal.
+func printDiff(unified string) {
+	if unified == "" {
+		fmt.Println("No differences.")
+		return
+	}
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Print(unified)
+		return
+	}
+
+	for _, line := range strings.SplitAfter(unified, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			fmt.Print("\033[1m" + strings.TrimSuffix(line, "\n") + "\033[0m\n")
+		case strings.HasPrefix(line, "@@"):
+			fmt.Print("\033[36m" + strings.TrimSuffix(line, "\n") + "\033[0m\n")
+		case strings.HasPrefix(line, "+"):
+			fmt.Print("\033[32m" + strings.TrimSuffix(line, "\n") + "\033[0m\n")
+		case strings.HasPrefix(line, "-"):
+			fmt.Print("\033[31m" + strings.TrimSuffix(line, "\n") + "\033[0m\n")
+		default:
+			fmt.Print(line)
+		}
+	}
+}
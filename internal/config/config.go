@@ -0,0 +1,208 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Environment variables selecting the database, see DBPath
+const (
+	EnvDB      = "BASHHUB_DB"
+	EnvProfile = "BASHHUB_PROFILE"
+)
+
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Config holds user settings read from config.json in the config directory.
+type Config struct {
+	// Interpreters maps a lower-case language name, as detected for a
+	// script, to the command used to run it, e.g. "python": "python3 -u".
+	Interpreters map[string]string `json:"interpreters"`
+	// Profiles maps a profile name to a database file, e.g. a shared
+	// "team": "/mnt/shared/bashhub.db". Profiles not listed here get their
+	// own database in the data directory.
+	Profiles map[string]string `json:"profiles"`
+	// SecretsKeyFile is a file whose contents unlock the secrets, used
+	// instead of asking for a passphrase.
+	SecretsKeyFile string `json:"secrets_key_file"`
+}
+
+// DefaultInterpreters is used for any language not set in config.json.
+var DefaultInterpreters = map[string]string{
+	"bash":       "bash",
+	"sh":         "sh",
+	"zsh":        "zsh",
+	"fish":       "fish",
+	"tcsh":       "tcsh",
+	"python":     "python3",
+	"javascript": "node",
+	"ruby":       "ruby",
+	"perl":       "perl",
+	"php":        "php",
+	"powershell": "pwsh",
+	"lua":        "lua",
+	"awk":        "awk -f",
+	"jq":         "jq -n -f",
+}
+
+// Dir returns the bashhub configuration directory, creating it if needed.
+// $XDG_CONFIG_HOME is honoured on every platform.
+func Dir() (string, error) {
+	var baseDir string
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		baseDir = filepath.Join(xdg, "bashhub")
+	} else {
+		// ~/.config on Linux, ~/Library/Application Support on macOS
+		userDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot locate a config directory: %w", err)
+		}
+		baseDir = filepath.Join(userDir, "bashhub")
+	}
+
+	if err := os.MkdirAll(baseDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return baseDir, nil
+}
+
+// DataDir returns the directory databases are kept in: $XDG_DATA_HOME/bashhub
+// when set, otherwise the config directory, where bashhub has always kept them.
+func DataDir() (string, error) {
+	xdg := os.Getenv("XDG_DATA_HOME")
+	if xdg == "" {
+		return Dir()
+	}
+
+	baseDir := filepath.Join(xdg, "bashhub")
+	if err := os.MkdirAll(baseDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return baseDir, nil
+}
+
+// DBPath picks the database file. In order of precedence: the dbFlag path,
+// the profile flag, $BASHHUB_DB, $BASHHUB_PROFILE, then bashhub.db in the
+// data directory. A profile resolves through cfg.Profiles, falling back to
+// profiles/<name>.db in the data directory.
+func (cfg Config) DBPath(dbFlag, profile string) (string, error) {
+	if dbFlag != "" && profile != "" {
+		return "", errors.New("--db and --profile cannot be used together")
+	}
+
+	path := dbFlag
+	if path == "" && profile == "" {
+		path = os.Getenv(EnvDB)
+		if path == "" {
+			profile = os.Getenv(EnvProfile)
+		}
+	}
+
+	if path == "" && profile != "" {
+		if !profileName.MatchString(profile) {
+			return "", fmt.Errorf("invalid profile name '%s'", profile)
+		}
+		path = cfg.Profiles[profile]
+		if path == "" {
+			var err error
+			if path, err = dataPath(filepath.Join("profiles", profile+".db")); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if path == "" {
+		return dataPath("bashhub.db")
+	}
+
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create database directory: %w", err)
+	}
+	return path, nil
+}
+
+// dataPath returns the file rel in the data directory. A database created in
+// the config directory before $XDG_DATA_HOME was honoured keeps being used
+// from there, with a notice, until it is moved.
+func dataPath(rel string) (string, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dataDir, rel)
+	if os.Getenv("XDG_DATA_HOME") == "" {
+		return path, nil
+	}
+
+	configDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	legacy := filepath.Join(configDir, rel)
+	if legacy == path || !exists(legacy) || exists(path) {
+		return path, nil
+	}
+	fmt.Fprintf(os.Stderr, "Using %s; move it to %s to keep it in $XDG_DATA_HOME\n", legacy, path)
+	return legacy, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// Load reads config.json, filling in defaults. A missing file is not an error.
+func Load() (Config, error) {
+	cfg := Config{Interpreters: make(map[string]string)}
+	for lang, cmd := range DefaultInterpreters {
+		cfg.Interpreters[lang] = cmd
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return cfg, err
+	}
+
+	path := filepath.Join(dir, "config.json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	} else if err != nil {
+		return cfg, err
+	}
+
+	var file Config
+	if err := json.Unmarshal(data, &file); err != nil {
+		return cfg, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	for lang, cmd := range file.Interpreters {
+		cfg.Interpreters[strings.ToLower(lang)] = cmd
+	}
+	cfg.Profiles = file.Profiles
+	if cfg.SecretsKeyFile, err = expandHome(file.SecretsKeyFile); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
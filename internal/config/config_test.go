@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDBPathKeepsConfigDirDatabase(t *testing.T) {
+	configHome := t.TempDir()
+	dataHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	t.Setenv(EnvDB, "")
+	t.Setenv(EnvProfile, "")
+
+	legacy := filepath.Join(configHome, "bashhub", "bashhub.db")
+	moved := filepath.Join(dataHome, "bashhub", "bashhub.db")
+	legacyProfile := filepath.Join(configHome, "bashhub", "profiles", "work.db")
+	for _, path := range []string{legacy, legacyProfile} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		setup   func()
+		want    string
+	}{
+		{"existing database is kept", "", nil, legacy},
+		{"existing profile is kept", "work", nil, legacyProfile},
+		{"new profile goes to the data directory", "home", nil, filepath.Join(dataHome, "bashhub", "profiles", "home.db")},
+		{"moved database wins", "", func() { os.WriteFile(moved, nil, 0o600) }, moved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			got, err := Config{}.DBPath("", tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("DBPath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
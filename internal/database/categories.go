@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CategorySeparator separates the levels of a nested category, as in
+// "AWS/EC2".
+const CategorySeparator = "/"
+
+// SplitCategory splits a category into its levels, trimming spaces and
+// dropping empty levels.
+func SplitCategory(category string) []string {
+	var levels []string
+	for _, level := range strings.Split(category, CategorySeparator) {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// CleanCategory returns category with its levels trimmed and empty levels
+// dropped, so " AWS//EC2 " becomes "AWS/EC2".
+func CleanCategory(category string) string {
+	return strings.Join(SplitCategory(category), CategorySeparator)
+}
+
+// InCategory reports whether category is parent or one of its
+// subcategories.
+func InCategory(category, parent string) bool {
+	category, parent = CleanCategory(category), CleanCategory(parent)
+	return category == parent || strings.HasPrefix(category, parent+CategorySeparator)
+}
+
+// MoveCategory moves the scripts in category from and its subcategories
+// under to, keeping the levels below from: moving "AWS" to "Cloud/AWS" turns
+// "AWS/EC2" into "Cloud/AWS/EC2". It returns the number of scripts moved.
+func MoveCategory(db *sqlx.DB, from, to string) (int, error) {
+	from, to = CleanCategory(from), CleanCategory(to)
+	if from == "" || to == "" {
+		return 0, errors.New("category cannot be empty")
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var scripts []Script
+	if err := tx.Select(&scripts, "SELECT id, category FROM scripts"); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, script := range scripts {
+		if !InCategory(script.Category, from) {
+			continue
+		}
+		category := to + strings.TrimPrefix(CleanCategory(script.Category), from)
+		if _, err := tx.Exec("UPDATE scripts SET category=? WHERE id=?", category, script.ID); err != nil {
+			return 0, err
+		}
+		moved++
+	}
+	return moved, tx.Commit()
+}
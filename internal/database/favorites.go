@@ -0,0 +1,33 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetFavorites returns the IDs of the favorite scripts
+func GetFavorites(db *sqlx.DB) (map[int64]bool, error) {
+	var ids []int64
+	if err := db.Select(&ids, "SELECT script_id FROM favorites"); err != nil {
+		return nil, err
+	}
+	favorites := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		favorites[id] = true
+	}
+	return favorites, nil
+}
+
+// SetFavorite pins a script as a favorite, or unpins it
+func SetFavorite(db *sqlx.DB, scriptID int64, favorite bool) error {
+	if !favorite {
+		_, err := db.Exec("DELETE FROM favorites WHERE script_id=?", scriptID)
+		return err
+	}
+	_, err := db.Exec(
+		"INSERT OR IGNORE INTO favorites (script_id, created_at) VALUES (?, ?)",
+		scriptID, time.Now().UTC(),
+	)
+	return err
+}
@@ -0,0 +1,228 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one versioned schema change. Most live in migrations/ as
+// NNNN_description.sql; changes SQL alone can't express idempotently are
+// registered in goMigrations instead.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sqlx.Tx) error
+}
+
+// MigrationState pairs a known migration with when it was applied.
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// goMigrations are applied in version order alongside the SQL files.
+var goMigrations = []Migration{
+	{
+		// Added ad hoc before migrations existed, so they may already be there
+		Version: 2,
+		Name:    "add_script_settings",
+		Up: func(tx *sqlx.Tx) error {
+			if err := addColumnIfMissing(tx, "scripts", "raw_placeholders", "BOOLEAN NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "scripts", "interpreter", "TEXT NOT NULL DEFAULT ''")
+		},
+	},
+}
+
+const migrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at DATETIME NOT NULL
+);`
+
+// Migrations returns every migration known to this binary, in order.
+func Migrations() ([]Migration, error) {
+	migrations := append([]Migration(nil), goMigrations...)
+
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		number, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: file name must start with a version number", file)
+		}
+		body, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		sql := string(body)
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    name,
+			Up: func(tx *sqlx.Tx) error {
+				_, err := tx.Exec(sql)
+				return err
+			},
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// MigrationStatus lists known migrations and whether each has been applied.
+func MigrationStatus(db *sqlx.DB) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i].Migration = m
+		if at, ok := applied[m.Version]; ok {
+			states[i].AppliedAt = &at
+		}
+	}
+	return states, nil
+}
+
+// Migrate applies all pending migrations in a single transaction and returns
+// the ones applied. If the database already holds data, it is first copied
+// to a timestamped backup next to dbPath.
+func Migrate(db *sqlx.DB, dbPath string) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := migrations[len(migrations)-1].Version
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	for version := range applied {
+		if version > latest {
+			return nil, fmt.Errorf("database schema version %d is newer than this bashhub supports (%d); please upgrade bashhub", version, latest)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	if dbPath != "" {
+		if err := backupDB(db, dbPath); err != nil {
+			return nil, fmt.Errorf("backup before migrating failed: %w", err)
+		}
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migrationsTable); err != nil {
+		return nil, err
+	}
+	for _, m := range pending {
+		if err := m.Up(tx); err != nil {
+			return nil, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			m.Version, m.Name, time.Now().UTC(),
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	return pending, tx.Commit()
+}
+
+func appliedMigrations(db *sqlx.DB) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+
+	var exists int
+	if err := db.Get(&exists, "SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations'"); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return applied, nil
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// backupDB snapshots a non-empty database with VACUUM INTO.
+func backupDB(db *sqlx.DB, dbPath string) error {
+	var tables int
+	if err := db.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE type='table'"); err != nil || tables == 0 {
+		return err
+	}
+
+	backup := fmt.Sprintf("%s.backup-%s", dbPath, time.Now().Format("20060102-150405"))
+	if _, err := os.Stat(backup); err == nil {
+		return fmt.Errorf("%s already exists", backup)
+	}
+	_, err := db.Exec("VACUUM INTO ?", backup)
+	return err
+}
+
+func addColumnIfMissing(tx *sqlx.Tx, table, column, definition string) error {
+	var count int
+	err := tx.Get(&count, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?", table, column)
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
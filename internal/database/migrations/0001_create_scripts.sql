@@ -0,0 +1,10 @@
+-- The original bashhub schema. IF NOT EXISTS lets databases created before
+-- migrations existed adopt the migration history.
+CREATE TABLE IF NOT EXISTS scripts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	description TEXT,
+	content TEXT NOT NULL,
+	language TEXT DEFAULT 'bash',
+	category TEXT DEFAULT 'General'
+);
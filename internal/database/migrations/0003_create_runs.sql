@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	script_id INTEGER NOT NULL REFERENCES scripts(id),
+	inputs TEXT NOT NULL DEFAULT '{}',
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME,
+	exit_code INTEGER,
+	output TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_runs_script ON runs(script_id, started_at);
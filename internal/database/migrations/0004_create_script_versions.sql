@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS script_versions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	script_id INTEGER NOT NULL REFERENCES scripts(id),
+	version INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	author TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	UNIQUE (script_id, version)
+);
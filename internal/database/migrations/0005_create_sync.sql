@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS settings (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+-- Files mirrored to the sync working tree as of the last sync, so changes
+-- on either side can be told apart.
+CREATE TABLE IF NOT EXISTS sync_files (
+	path TEXT PRIMARY KEY,
+	script_name TEXT NOT NULL,
+	hash TEXT NOT NULL
+);
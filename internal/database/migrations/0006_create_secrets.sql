@@ -0,0 +1,7 @@
+-- Secret values, encrypted by the secrets package; the key never touches
+-- the database.
+CREATE TABLE IF NOT EXISTS secrets (
+	name TEXT PRIMARY KEY,
+	value BLOB NOT NULL,
+	updated_at DATETIME NOT NULL
+);
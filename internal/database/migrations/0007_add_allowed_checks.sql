@@ -0,0 +1,2 @@
+-- Dangerous-command checks a script is trusted to trigger, space separated
+ALTER TABLE scripts ADD COLUMN allowed_checks TEXT NOT NULL DEFAULT '';
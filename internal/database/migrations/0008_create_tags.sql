@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE
+);
+
+-- A script has any number of tags, and a tag any number of scripts
+CREATE TABLE IF NOT EXISTS script_tags (
+	script_id INTEGER NOT NULL REFERENCES scripts(id),
+	tag_id INTEGER NOT NULL REFERENCES tags(id),
+	PRIMARY KEY (script_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS script_tags_tag ON script_tags(tag_id);
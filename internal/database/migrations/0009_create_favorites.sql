@@ -0,0 +1,6 @@
+-- Scripts pinned to the top of the TUI tree. Kept apart from scripts so
+-- pins stay local and don't travel with exports or sync.
+CREATE TABLE IF NOT EXISTS favorites (
+	script_id INTEGER PRIMARY KEY REFERENCES scripts(id),
+	created_at DATETIME NOT NULL
+);
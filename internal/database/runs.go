@@ -0,0 +1,165 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxRunOutput caps the captured output stored per run. Only the tail is kept.
+const MaxRunOutput = 64 * 1024
+
+// ExitCodeError is recorded for runs that failed to start or to finish
+// normally, with the error at the end of the output.
+const ExitCodeError = -1
+
+// Run statuses accepted by RunFilter
+const (
+	RunStatusRunning = "running"
+	RunStatusSuccess = "success"
+	RunStatusFailed  = "failed"
+)
+
+type Run struct {
+	ID         int64         `db:"id"`
+	ScriptID   int64         `db:"script_id"`
+	ScriptName string        `db:"script_name"`
+	Inputs     string        `db:"inputs"`
+	StartedAt  time.Time     `db:"started_at"`
+	FinishedAt sql.NullTime  `db:"finished_at"`
+	ExitCode   sql.NullInt64 `db:"exit_code"`
+	Output     string        `db:"output"`
+}
+
+// Status returns running, success or failed
+func (r Run) Status() string {
+	switch {
+	case !r.FinishedAt.Valid:
+		return RunStatusRunning
+	case r.ExitCode.Int64 == 0:
+		return RunStatusSuccess
+	default:
+		return RunStatusFailed
+	}
+}
+
+// Duration returns how long the run took, or zero while it is still running
+func (r Run) Duration() time.Duration {
+	if !r.FinishedAt.Valid {
+		return 0
+	}
+	return r.FinishedAt.Time.Sub(r.StartedAt)
+}
+
+// RunFilter narrows GetRuns; zero values match everything
+type RunFilter struct {
+	ScriptName string
+	Status     string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+}
+
+// StartRun records the start of a script execution and returns the run ID
+func StartRun(db *sqlx.DB, scriptID int64, inputs map[string]string) (int64, error) {
+	encoded, err := json.Marshal(inputs)
+	if err != nil {
+		return 0, err
+	}
+	if inputs == nil {
+		encoded = []byte("{}")
+	}
+
+	res, err := db.Exec(
+		"INSERT INTO runs (script_id, inputs, started_at) VALUES (?, ?, ?)",
+		scriptID, string(encoded), time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// FinishRun stores the exit code and captured output of a run
+func FinishRun(db *sqlx.DB, id int64, exitCode int, output string) error {
+	_, err := db.Exec(
+		"UPDATE runs SET finished_at=?, exit_code=?, output=? WHERE id=?",
+		time.Now().UTC(), exitCode, tail(output, MaxRunOutput), id,
+	)
+	return err
+}
+
+// GetRuns lists runs, newest first
+func GetRuns(db *sqlx.DB, filter RunFilter) ([]Run, error) {
+	query := `SELECT runs.*, scripts.name AS script_name
+		FROM runs JOIN scripts ON scripts.id = runs.script_id`
+	var where []string
+	var args []interface{}
+
+	if filter.ScriptName != "" {
+		where = append(where, "scripts.name = ?")
+		args = append(args, filter.ScriptName)
+	}
+	switch filter.Status {
+	case RunStatusRunning:
+		where = append(where, "runs.finished_at IS NULL")
+	case RunStatusSuccess:
+		where = append(where, "runs.finished_at IS NOT NULL AND runs.exit_code = 0")
+	case RunStatusFailed:
+		where = append(where, "runs.finished_at IS NOT NULL AND runs.exit_code != 0")
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "runs.started_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "runs.started_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY runs.started_at DESC, runs.id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	var runs []Run
+	err := db.Select(&runs, query, args...)
+	return runs, err
+}
+
+// OutputTail accumulates streamed output, keeping at most MaxRunOutput bytes
+type OutputTail struct {
+	buf []byte
+}
+
+func (o *OutputTail) Write(chunk string) {
+	o.buf = append(o.buf, chunk...)
+	// Trim lazily so long runs don't copy on every chunk
+	if len(o.buf) > 2*MaxRunOutput {
+		o.buf = append(o.buf[:0], tail(string(o.buf), MaxRunOutput)...)
+	}
+}
+
+func (o *OutputTail) String() string {
+	return tail(string(o.buf), MaxRunOutput)
+}
+
+func tail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	start := len(s) - max
+	// Start on a character boundary, not halfway through a UTF-8 sequence
+	for i := 0; i < utf8.UTFMax && start < len(s) && !utf8.RuneStart(s[start]); i++ {
+		start++
+	}
+	return s[start:]
+}
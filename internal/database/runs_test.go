@@ -0,0 +1,28 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTailKeepsCharactersWhole(t *testing.T) {
+	s := strings.Repeat("é", 10) // two bytes each
+	for max := 0; max <= len(s); max++ {
+		got := tail(s, max)
+		if !utf8.ValidString(got) || len(got) > max || len(got) < max-1 {
+			t.Errorf("tail(%d) = %q", max, got)
+		}
+	}
+}
+
+func TestOutputTailKeepsCharactersWhole(t *testing.T) {
+	var o OutputTail
+	o.Write("x")
+	for range MaxRunOutput / 1000 {
+		o.Write(strings.Repeat("€", 1000)) // three bytes each
+	}
+	if got := o.String(); !utf8.ValidString(got) || len(got) > MaxRunOutput {
+		t.Errorf("output tail is %d bytes, valid UTF-8 %v", len(got), utf8.ValidString(got))
+	}
+}
@@ -2,51 +2,31 @@ package database
 
 import (
 	"log"
-	"os"
-	"path/filepath"
-	"runtime"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var Schema = `
-CREATE TABLE IF NOT EXISTS scripts (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	name TEXT NOT NULL UNIQUE,
-	description TEXT,
-	content TEXT NOT NULL,
-	language TEXT DEFAULT 'bash',
-	category TEXT DEFAULT 'General'
-);`
+// ConnectDB opens the database at dbPath and brings its schema up to date.
+func ConnectDB(dbPath string) *sqlx.DB {
+	db := OpenDB(dbPath)
 
-func getDBPath() string {
-    var baseDir string
-
-    switch runtime.GOOS {
-    case "darwin":
-        baseDir = filepath.Join(os.Getenv("HOME"), "Library", "Application Support", "bashhub")
-    case "linux":
-        baseDir = filepath.Join(os.Getenv("HOME"), ".config", "bashhub")
-    default:
-        log.Fatal("Unsupported OS")
-    }
+	if _, err := Migrate(db, dbPath); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
 
-    if err := os.MkdirAll(baseDir, os.ModePerm); err != nil {
-        log.Fatalf("Failed to create config directory: %v", err)
-    }
+	if err := setupSearch(db); err != nil {
+		log.Fatalf("Failed to set up search index: %v", err)
+	}
 
-    return filepath.Join(baseDir, "bashhub.db")
+	return db
 }
 
-
-func ConnectDB() *sqlx.DB {
-	db, err := sqlx.Connect("sqlite3", getDBPath())
+// OpenDB opens the database without migrating it.
+func OpenDB(dbPath string) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", dbPath)
 	if err != nil {
 		log.Fatalln(err)
 	}
-
-	db.MustExec(Schema)
-
 	return db
 }
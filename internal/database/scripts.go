@@ -1,57 +1,205 @@
 package database
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
 )
 
+// ErrScriptExists is returned when a script name is already taken
+var ErrScriptExists = errors.New("a script with this name already exists")
+
+// maxNameLength bounds script names, which also become file names on export
+const maxNameLength = 100
+
 type Script struct {
-	ID          int64  `db:"id"`
-	Name        string `db:"name"`
-	Description string `db:"description"`
-	Content     string `db:"content"`
-	Category    string `db:"category"`
-	Language    string `db:"language"`
+	ID          int64  `db:"id" json:"id"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+	Content     string `db:"content" json:"content"`
+	Category    string `db:"category" json:"category"`
+	Language    string `db:"language" json:"language"`
+	// RawPlaceholders disables shell quoting of substituted values
+	RawPlaceholders bool `db:"raw_placeholders" json:"raw_placeholders"`
+	// Interpreter overrides the shebang and language based interpreter
+	Interpreter string `db:"interpreter" json:"interpreter,omitempty"`
+	// AllowedChecks lists the dangerous-command checks not to warn about,
+	// separated by spaces
+	AllowedChecks string `db:"allowed_checks" json:"allowed_checks,omitempty"`
+	// Tags are kept in the tags table, lowercase and sorted
+	Tags []string `db:"-" json:"tags,omitempty"`
+}
+
+// ValidateName checks that name is usable as a script name: not blank,
+// without surrounding spaces, control characters or path separators.
+func ValidateName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("script name cannot be empty")
+	case strings.TrimSpace(name) != name:
+		return errors.New("script name cannot start or end with spaces")
+	case len(name) > maxNameLength:
+		return fmt.Errorf("script name cannot be longer than %d characters", maxNameLength)
+	case name == "." || name == "..":
+		return fmt.Errorf("'%s' is not a valid script name", name)
+	case strings.ContainsAny(name, `/\`):
+		return errors.New("script name cannot contain slashes")
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return errors.New("script name cannot contain control characters")
+	}
+	return nil
 }
 
-// CreateScript adds a new script to the database
+// CreateScript adds a new script to the database and records it as version 1
 func CreateScript(db *sqlx.DB, script Script) error {
-	_, err := db.Exec(
-		"INSERT INTO scripts (name, description, content, category) VALUES (?, ?, ?, ?)",
-		script.Name, script.Description, script.Content, script.Category,
+	if err := ValidateName(script.Name); err != nil {
+		return err
+	}
+	tags, err := NormalizeTags(script.Tags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"INSERT INTO scripts (name, description, content, category, language, raw_placeholders, interpreter, allowed_checks) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		script.Name, script.Description, script.Content, script.Category, script.Language, script.RawPlaceholders, script.Interpreter, script.AllowedChecks,
 	)
-	return err
+	if err != nil {
+		return nameConflict(err)
+	}
+	if script.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	if err := setScriptTags(tx, script.ID, tags); err != nil {
+		return err
+	}
+
+	if err := recordVersion(tx, script, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetScripts retrieves all scripts
 func GetScripts(db *sqlx.DB) ([]Script, error) {
 	var scripts []Script
-	err := db.Select(&scripts, "SELECT * FROM scripts ORDER BY name")
-	return scripts, err
+	if err := db.Select(&scripts, "SELECT * FROM scripts ORDER BY name"); err != nil {
+		return nil, err
+	}
+	return scripts, loadTags(db, scripts)
 }
 
 func GetScriptByID(db *sqlx.DB, id int64) (Script, error) {
 	var script Script
-	err := db.Get(&script, "SELECT * FROM scripts WHERE id=?", id)
-	return script, err
+	if err := db.Get(&script, "SELECT * FROM scripts WHERE id=?", id); err != nil {
+		return script, err
+	}
+	return withTags(db, script)
 }
 
 func GetScriptByName(db *sqlx.DB, name string) (Script, error) {
 	var script Script
-	err := db.Get(&script, "SELECT * FROM scripts WHERE name=?", name)
-	return script, err
+	if err := db.Get(&script, "SELECT * FROM scripts WHERE name=?", name); err != nil {
+		return script, err
+	}
+	return withTags(db, script)
 }
 
-// UpdateScript updates an existing script
+// UpdateScript updates an existing script, recording a new version when its
+// content or description changed
 func UpdateScript(db *sqlx.DB, script Script) error {
-	_, err := db.Exec(
-		"UPDATE scripts SET name=?, description=?, content=?, category=? WHERE id=?",
-		script.Name, script.Description, script.Content, script.Category, script.ID,
+	tags, err := NormalizeTags(script.Tags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previous Script
+	if err := tx.Get(&previous, "SELECT * FROM scripts WHERE id=?", script.ID); err != nil {
+		return err
+	}
+	// Names from before validation existed are kept as they are
+	if script.Name != previous.Name {
+		if err := ValidateName(script.Name); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE scripts SET name=?, description=?, content=?, category=?, language=?, raw_placeholders=?, interpreter=?, allowed_checks=? WHERE id=?",
+		script.Name, script.Description, script.Content, script.Category, script.Language, script.RawPlaceholders, script.Interpreter, script.AllowedChecks, script.ID,
 	)
+	if err != nil {
+		return nameConflict(err)
+	}
+	if err := setScriptTags(tx, script.ID, tags); err != nil {
+		return err
+	}
+
+	if err := recordVersion(tx, script, &previous); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CopyScript saves a duplicate of script under a new name. The copy starts
+// its own history.
+func CopyScript(db *sqlx.DB, script Script, name string) (Script, error) {
+	script.ID = 0
+	script.Name = name
+	if err := CreateScript(db, script); err != nil {
+		return Script{}, err
+	}
+	return GetScriptByName(db, name)
+}
+
+// nameConflict turns a violation of the unique name into ErrScriptExists
+func nameConflict(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrScriptExists
+	}
 	return err
 }
 
-// DeleteScript deletes a script by ID
+// DeleteScript deletes a script with its run, version history, tags and
+// favorite pin by ID
 func DeleteScript(db *sqlx.DB, id int64) error {
-	_, err := db.Exec("DELETE FROM scripts WHERE id=?", id)
-	return err
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM runs WHERE script_id=?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM script_versions WHERE script_id=?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM scripts WHERE id=?", id); err != nil {
+		return err
+	}
+	if err := setScriptTags(tx, id, nil); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM favorites WHERE script_id=?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
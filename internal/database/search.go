@@ -0,0 +1,213 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Markers wrapped around matched terms in SearchResult.Snippet
+const (
+	MatchStart = "\x01"
+	MatchEnd   = "\x02"
+)
+
+// The FTS index mirrors name, description and content of scripts. Triggers
+// keep it in sync; they only exist while the binary supports FTS5, because
+// with the module missing they would make every write to scripts fail.
+var searchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS scripts_fts USING fts5(
+	name, description, content,
+	content='scripts', content_rowid='id'
+);
+
+CREATE TRIGGER IF NOT EXISTS scripts_fts_insert AFTER INSERT ON scripts BEGIN
+	INSERT INTO scripts_fts(rowid, name, description, content)
+	VALUES (new.id, new.name, new.description, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS scripts_fts_delete AFTER DELETE ON scripts BEGIN
+	INSERT INTO scripts_fts(scripts_fts, rowid, name, description, content)
+	VALUES ('delete', old.id, old.name, old.description, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS scripts_fts_update AFTER UPDATE ON scripts BEGIN
+	INSERT INTO scripts_fts(scripts_fts, rowid, name, description, content)
+	VALUES ('delete', old.id, old.name, old.description, old.content);
+	INSERT INTO scripts_fts(rowid, name, description, content)
+	VALUES (new.id, new.name, new.description, new.content);
+END;`
+
+var searchTriggers = []string{"scripts_fts_insert", "scripts_fts_delete", "scripts_fts_update"}
+
+type SearchResult struct {
+	Script
+	// Rank orders results, lower is better
+	Rank float64 `db:"rank"`
+	// Snippet is an excerpt around the best match, with matched terms
+	// wrapped in MatchStart and MatchEnd
+	Snippet string `db:"snippet"`
+}
+
+// setupSearch creates the FTS index when the binary supports FTS5 and
+// rebuilds it if it was out of sync. Without FTS5 the sync triggers are
+// removed and SearchScripts falls back to LIKE matching.
+func setupSearch(db *sqlx.DB) error {
+	if !ftsAvailable(db) {
+		for _, trigger := range searchTriggers {
+			if _, err := db.Exec("DROP TRIGGER IF EXISTS " + trigger); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var count int
+	err := db.Get(&count,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type='trigger' AND name IN (?, ?, ?)",
+		searchTriggers[0], searchTriggers[1], searchTriggers[2])
+	if err != nil || count == len(searchTriggers) {
+		return err
+	}
+
+	// The index is new, or was left stale by a build without FTS5
+	if _, err := db.Exec(searchSchema); err != nil {
+		return err
+	}
+	_, err = db.Exec("INSERT INTO scripts_fts(scripts_fts) VALUES ('rebuild')")
+	return err
+}
+
+func ftsAvailable(db *sqlx.DB) bool {
+	var enabled bool
+	err := db.Get(&enabled, "SELECT sqlite_compileoption_used('ENABLE_FTS5')")
+	return err == nil && enabled
+}
+
+// SearchScripts returns scripts matching every word of query in their name,
+// description or content, best matches first. Words match as prefixes.
+func SearchScripts(db *sqlx.DB, query string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+	if !ftsAvailable(db) {
+		return searchScriptsLike(db, terms, limit)
+	}
+
+	var quoted []string
+	for _, term := range terms {
+		quoted = append(quoted, `"`+term+`"*`)
+	}
+
+	var results []SearchResult
+	err := db.Select(&results, `
+		SELECT scripts.*,
+			bm25(scripts_fts, 10.0, 5.0, 1.0) AS rank,
+			snippet(scripts_fts, -1, ?, ?, '…', 12) AS snippet
+		FROM scripts_fts JOIN scripts ON scripts.id = scripts_fts.rowid
+		WHERE scripts_fts MATCH ?
+		ORDER BY rank, scripts.name
+		LIMIT ?`,
+		MatchStart, MatchEnd, strings.Join(quoted, " "), limit)
+	if err != nil {
+		return nil, err
+	}
+	return results, loadResultTags(db, results)
+}
+
+// searchScriptsLike is the fallback for binaries built without FTS5. Name
+// matches rank above description matches, which rank above content matches.
+func searchScriptsLike(db *sqlx.DB, terms []string, limit int) ([]SearchResult, error) {
+	var rank, where []string
+	var rankArgs, whereArgs []interface{}
+	escape := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	for _, term := range terms {
+		like := "%" + escape.Replace(term) + "%"
+		rank = append(rank, `(CASE WHEN name LIKE ? ESCAPE '\' THEN 10 WHEN description LIKE ? ESCAPE '\' THEN 5 ELSE 1 END)`)
+		rankArgs = append(rankArgs, like, like)
+		where = append(where, `(name LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR content LIKE ? ESCAPE '\')`)
+		whereArgs = append(whereArgs, like, like, like)
+	}
+
+	query := fmt.Sprintf(`SELECT scripts.*, -(%s) AS rank, '' AS snippet
+		FROM scripts WHERE %s ORDER BY rank, name LIMIT ?`,
+		strings.Join(rank, " + "), strings.Join(where, " AND "))
+	args := append(append(rankArgs, whereArgs...), limit)
+
+	var results []SearchResult
+	if err := db.Select(&results, query, args...); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Snippet = likeSnippet(results[i].Script, terms)
+	}
+	return results, loadResultTags(db, results)
+}
+
+// loadResultTags fills in the Tags of search results
+func loadResultTags(db *sqlx.DB, results []SearchResult) error {
+	scripts := make([]Script, len(results))
+	for i, result := range results {
+		scripts[i] = result.Script
+	}
+	if err := loadTags(db, scripts); err != nil {
+		return err
+	}
+	for i := range results {
+		results[i].Tags = scripts[i].Tags
+	}
+	return nil
+}
+
+// likeSnippet picks the first line mentioning a term and marks the matches.
+func likeSnippet(script Script, terms []string) string {
+	for _, field := range []string{script.Content, script.Description, script.Name} {
+		for _, line := range strings.Split(field, "\n") {
+			lower := strings.ToLower(line)
+			for _, term := range terms {
+				if strings.Contains(lower, term) {
+					return markTerms(strings.TrimSpace(line), terms)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func markTerms(line string, terms []string) string {
+	lower := strings.ToLower(line)
+	if len(lower) != len(line) {
+		return line // case mapping changed byte offsets
+	}
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		matched := 0
+		for _, term := range terms {
+			if strings.HasPrefix(lower[i:], term) && len(term) > matched {
+				matched = len(term)
+			}
+		}
+		if matched == 0 {
+			b.WriteByte(line[i])
+			i++
+			continue
+		}
+		b.WriteString(MatchStart + line[i:i+matched] + MatchEnd)
+		i += matched
+	}
+	return b.String()
+}
+
+// searchTerms splits a query into lower-case words, the way the FTS5
+// unicode61 tokenizer does.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
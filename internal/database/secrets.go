@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Secret is an encrypted secret value, see the secrets package.
+type Secret struct {
+	Name      string    `db:"name" json:"name"`
+	Value     []byte    `db:"value" json:"-"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func GetSecret(db *sqlx.DB, name string) (Secret, error) {
+	var secret Secret
+	err := db.Get(&secret, "SELECT * FROM secrets WHERE name=?", name)
+	return secret, err
+}
+
+// GetSecrets lists the secrets by name, without their values
+func GetSecrets(db *sqlx.DB) ([]Secret, error) {
+	var secrets []Secret
+	err := db.Select(&secrets, "SELECT name, updated_at FROM secrets ORDER BY name")
+	return secrets, err
+}
+
+func SetSecret(db *sqlx.DB, name string, value []byte) error {
+	_, err := db.Exec(
+		"INSERT INTO secrets (name, value, updated_at) VALUES (?, ?, ?) ON CONFLICT(name) DO UPDATE SET value=excluded.value, updated_at=excluded.updated_at",
+		name, value, time.Now().UTC(),
+	)
+	return err
+}
+
+// DeleteSecret removes a secret, returning sql.ErrNoRows if there is none
+func DeleteSecret(db *sqlx.DB, name string) error {
+	res, err := db.Exec("DELETE FROM secrets WHERE name=?", name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
@@ -0,0 +1,23 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetSetting returns a stored setting, or "" if it isn't set
+func GetSetting(db *sqlx.DB, key string) (string, error) {
+	var value string
+	err := db.Get(&value, "SELECT value FROM settings WHERE key=?", key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+func SetSetting(db *sqlx.DB, key, value string) error {
+	_, err := db.Exec("INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value", key, value)
+	return err
+}
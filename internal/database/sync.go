@@ -0,0 +1,44 @@
+package database
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+// SyncFile is a script file in the sync working tree as of the last sync
+type SyncFile struct {
+	Path       string `db:"path"`
+	ScriptName string `db:"script_name"`
+	Hash       string `db:"hash"`
+}
+
+// GetSyncFiles returns the last synced files by path
+func GetSyncFiles(db *sqlx.DB) (map[string]SyncFile, error) {
+	var files []SyncFile
+	if err := db.Select(&files, "SELECT * FROM sync_files"); err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]SyncFile, len(files))
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+	return byPath, nil
+}
+
+// ReplaceSyncFiles records files as the state of the last sync
+func ReplaceSyncFiles(db *sqlx.DB, files []SyncFile) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM sync_files"); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if _, err := tx.Exec("INSERT INTO sync_files (path, script_name, hash) VALUES (?, ?, ?)", f.Path, f.ScriptName, f.Hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
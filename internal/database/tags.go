@@ -0,0 +1,163 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxTagLength bounds tag names
+const maxTagLength = 50
+
+// Tag is a tag name with the number of scripts carrying it.
+type Tag struct {
+	Name    string `db:"name" json:"name"`
+	Scripts int    `db:"scripts" json:"scripts"`
+}
+
+// ParseTags splits a list of tags separated by commas or spaces and
+// normalizes it with NormalizeTags.
+func ParseTags(value string) ([]string, error) {
+	return NormalizeTags(strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}))
+}
+
+// NormalizeTags lowercases tags, drops duplicates and sorts them. Tags
+// cannot contain spaces, commas or control characters.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		switch {
+		case tag == "":
+			continue
+		case len(tag) > maxTagLength:
+			return nil, fmt.Errorf("tag '%s' is longer than %d characters", tag, maxTagLength)
+		case strings.IndexFunc(tag, func(r rune) bool { return r == ',' || unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0:
+			return nil, fmt.Errorf("tag '%s' cannot contain spaces, commas or control characters", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// HasTags reports whether a script carries every one of tags.
+func (s Script) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range s.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTags returns every tag in use, by name, with its script count
+func GetTags(db *sqlx.DB) ([]Tag, error) {
+	var tags []Tag
+	err := db.Select(&tags, `
+		SELECT tags.name, COUNT(*) AS scripts
+		FROM tags JOIN script_tags ON script_tags.tag_id = tags.id
+		GROUP BY tags.id
+		ORDER BY tags.name`)
+	return tags, err
+}
+
+// SetScriptTags replaces the tags of a script
+func SetScriptTags(db *sqlx.DB, scriptID int64, tags []string) error {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setScriptTags(tx, scriptID, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setScriptTags replaces the tags of a script within tx, creating tags as
+// needed and dropping those no script uses any more.
+func setScriptTags(tx *sqlx.Tx, scriptID int64, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM script_tags WHERE script_id=?", scriptID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"INSERT OR IGNORE INTO script_tags (script_id, tag_id) SELECT ?, id FROM tags WHERE name=?",
+			scriptID, tag,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return deleteUnusedTags(tx)
+}
+
+func deleteUnusedTags(tx *sqlx.Tx) error {
+	_, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM script_tags)")
+	return err
+}
+
+// withTags returns script with its tags loaded
+func withTags(q sqlx.Queryer, script Script) (Script, error) {
+	scripts := []Script{script}
+	err := loadTags(q, scripts)
+	return scripts[0], err
+}
+
+// loadTags fills in the Tags of scripts
+func loadTags(q sqlx.Queryer, scripts []Script) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	query := `SELECT script_tags.script_id, tags.name
+		FROM script_tags JOIN tags ON tags.id = script_tags.tag_id`
+	var args []interface{}
+	if len(scripts) == 1 {
+		query += " WHERE script_tags.script_id=?"
+		args = append(args, scripts[0].ID)
+	}
+
+	var rows []struct {
+		ScriptID int64  `db:"script_id"`
+		Name     string `db:"name"`
+	}
+	if err := sqlx.Select(q, &rows, query+" ORDER BY tags.name", args...); err != nil {
+		return err
+	}
+
+	byScript := make(map[int64][]string)
+	for _, row := range rows {
+		byScript[row.ScriptID] = append(byScript[row.ScriptID], row.Name)
+	}
+	for i := range scripts {
+		scripts[i].Tags = byScript[scripts[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Usage is how often and how recently a script was run, from its runs
+type Usage struct {
+	ScriptID int64     `db:"script_id" json:"-"`
+	Runs     int       `db:"runs" json:"runs"`
+	LastRun  time.Time `db:"last_run" json:"last_run"`
+}
+
+// GetUsage returns the usage of every script run at least once, by script ID
+func GetUsage(db *sqlx.DB) (map[int64]Usage, error) {
+	// The newest run has the highest ID; joining on it keeps started_at
+	// typed as a DATETIME column
+	var rows []Usage
+	err := db.Select(&rows, `
+		SELECT counts.script_id, counts.runs, runs.started_at AS last_run
+		FROM (SELECT script_id, COUNT(*) AS runs, MAX(id) AS last_id FROM runs GROUP BY script_id) AS counts
+		JOIN runs ON runs.id = counts.last_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[int64]Usage, len(rows))
+	for _, row := range rows {
+		usage[row.ScriptID] = row
+	}
+	return usage, nil
+}
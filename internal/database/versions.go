@@ -0,0 +1,126 @@
+package database
+
+import (
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ScriptVersion struct {
+	ID          int64     `db:"id" json:"-"`
+	ScriptID    int64     `db:"script_id" json:"-"`
+	Version     int       `db:"version" json:"version"`
+	Content     string    `db:"content" json:"content"`
+	Description string    `db:"description" json:"description"`
+	Author      string    `db:"author" json:"author"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// GetScriptVersions lists the saved versions of a script, oldest first
+func GetScriptVersions(db *sqlx.DB, scriptID int64) ([]ScriptVersion, error) {
+	var versions []ScriptVersion
+	err := db.Select(&versions, "SELECT * FROM script_versions WHERE script_id=? ORDER BY version", scriptID)
+	return versions, err
+}
+
+func GetScriptVersion(db *sqlx.DB, scriptID int64, version int) (ScriptVersion, error) {
+	var v ScriptVersion
+	err := db.Get(&v, "SELECT * FROM script_versions WHERE script_id=? AND version=?", scriptID, version)
+	return v, err
+}
+
+// RollbackScript restores the content and description of an earlier
+// version. The rollback itself is recorded as a new version.
+func RollbackScript(db *sqlx.DB, script Script, version int) (Script, error) {
+	v, err := GetScriptVersion(db, script.ID, version)
+	if err != nil {
+		return script, err
+	}
+	script.Content = v.Content
+	script.Description = v.Description
+	return script, UpdateScript(db, script)
+}
+
+// RestoreScript creates a script together with the version history it had
+// elsewhere, e.g. in an exported bundle. Without versions it is recorded as
+// version 1, like CreateScript.
+func RestoreScript(db *sqlx.DB, script Script, versions []ScriptVersion) (Script, error) {
+	if len(versions) == 0 {
+		if err := CreateScript(db, script); err != nil {
+			return script, err
+		}
+		return GetScriptByName(db, script.Name)
+	}
+	if err := ValidateName(script.Name); err != nil {
+		return script, err
+	}
+	tags, err := NormalizeTags(script.Tags)
+	if err != nil {
+		return script, err
+	}
+	script.Tags = tags
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return script, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"INSERT INTO scripts (name, description, content, category, language, raw_placeholders, interpreter, allowed_checks) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		script.Name, script.Description, script.Content, script.Category, script.Language, script.RawPlaceholders, script.Interpreter, script.AllowedChecks,
+	)
+	if err != nil {
+		return script, nameConflict(err)
+	}
+	if script.ID, err = res.LastInsertId(); err != nil {
+		return script, err
+	}
+	if err := setScriptTags(tx, script.ID, tags); err != nil {
+		return script, err
+	}
+
+	for _, v := range versions {
+		_, err := tx.Exec(
+			"INSERT INTO script_versions (script_id, version, content, description, author, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+			script.ID, v.Version, v.Content, v.Description, v.Author, v.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return script, err
+		}
+	}
+	return script, tx.Commit()
+}
+
+// recordVersion stores the script's content as its next version. When a
+// script predates version tracking, previous is saved first as version 1 so
+// the content being replaced is never lost.
+func recordVersion(tx *sqlx.Tx, script Script, previous *Script) error {
+	var latest int
+	if err := tx.Get(&latest, "SELECT COALESCE(MAX(version), 0) FROM script_versions WHERE script_id=?", script.ID); err != nil {
+		return err
+	}
+
+	if previous != nil {
+		if previous.Content == script.Content && previous.Description == script.Description {
+			return nil // nothing versioned changed
+		}
+		if latest == 0 {
+			if err := insertVersion(tx, *previous, 1); err != nil {
+				return err
+			}
+			latest = 1
+		}
+	}
+
+	return insertVersion(tx, script, latest+1)
+}
+
+func insertVersion(tx *sqlx.Tx, script Script, version int) error {
+	_, err := tx.Exec(
+		"INSERT INTO script_versions (script_id, version, content, description, author, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		script.ID, version, script.Content, script.Description, os.Getenv("USER"), time.Now().UTC(),
+	)
+	return err
+}
@@ -0,0 +1,238 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kind of a line in an edit script
+type Kind byte
+
+const (
+	Equal  Kind = ' '
+	Delete Kind = '-'
+	Insert Kind = '+'
+)
+
+// Line is one line of an edit script turning a into b.
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+// Lines computes a shortest edit script between two line slices using the
+// linear space variant of Myers' algorithm, which splits the problem at the
+// middle snake of an optimal path and recurses on both halves. Within each
+// change, deletions come before insertions.
+func Lines(a, b []string) []Line {
+	size := 2*((len(a)+len(b)+1)/2) + 2
+	d := differ{a: a, b: b, forward: make([]int, size), backward: make([]int, size)}
+	d.compare(0, len(a), 0, len(b))
+
+	// Order each change as its deletions followed by its insertions
+	lines := d.lines
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == Equal {
+			i++
+			continue
+		}
+		end := i
+		for end < len(lines) && lines[end].Kind != Equal {
+			end++
+		}
+		sort.SliceStable(lines[i:end], func(x, y int) bool {
+			return lines[i+x].Kind == Delete && lines[i+y].Kind == Insert
+		})
+		i = end
+	}
+	return lines
+}
+
+type differ struct {
+	a, b  []string
+	lines []Line
+	// Furthest reaching x per diagonal, searching from either end
+	forward, backward []int
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.lines = append(d.lines, Line{Equal, d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for _, line := range d.b[bLo:bHi] {
+			d.lines = append(d.lines, Line{Insert, line})
+		}
+	case bLo == bHi:
+		for _, line := range d.a[aLo:aHi] {
+			d.lines = append(d.lines, Line{Delete, line})
+		}
+	default:
+		// Without a common prefix or suffix at least two edits are needed,
+		// so both halves are smaller problems
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for _, line := range d.a[x:u] {
+			d.lines = append(d.lines, Line{Equal, line})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for _, line := range d.a[aHi : aHi+suffix] {
+		d.lines = append(d.lines, Line{Equal, line})
+	}
+}
+
+// middleSnake finds the snake, from (x, y) to (u, v), in the middle of a
+// shortest edit path through a[aLo:aHi] and b[bLo:bHi], by searching from
+// both ends at once until the paths overlap.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	vf, vb := d.forward, d.backward
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	for D := 0; D <= maxD; D++ {
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			if kb := delta - k; odd && kb >= -(D-1) && kb <= D-1 && x+vb[offset+kb] >= n {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y
+			}
+		}
+
+		// The backward search runs over the reversed lines
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			if kf := delta - k; !odd && kf >= -D && kf <= D && x+vf[offset+kf] >= n {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0
+			}
+		}
+	}
+	panic("diff: no middle snake")
+}
+
+// Unified returns a unified diff of two texts with the given number of
+// context lines, or "" when they are equal.
+func Unified(a, b, fromLabel, toLabel string, context int) string {
+	lines := Lines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for i := 0; i < len(lines); {
+		// Find the next change
+		for i < len(lines) && lines[i].Kind == Equal {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines
+		start := max(i-context, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Kind == Equal {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				end = min(end+context, len(lines))
+				break
+			}
+			end = run
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromLabel, toLabel)
+		}
+		writeHunk(&out, lines, start, end)
+		i = end
+	}
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, lines []Line, start, end int) {
+	// Line numbers of the hunk start in a and b
+	aLine, bLine := 1, 1
+	for _, l := range lines[:start] {
+		if l.Kind != Insert {
+			aLine++
+		}
+		if l.Kind != Delete {
+			bLine++
+		}
+	}
+
+	var aCount, bCount int
+	for _, l := range lines[start:end] {
+		if l.Kind != Insert {
+			aCount++
+		}
+		if l.Kind != Delete {
+			bCount++
+		}
+	}
+	// An empty range is numbered by the line before it
+	if aCount == 0 {
+		aLine--
+	}
+	if bCount == 0 {
+		bLine--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+	for _, l := range lines[start:end] {
+		out.WriteByte(byte(l.Kind))
+		out.WriteString(l.Text)
+		out.WriteByte('\n')
+	}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
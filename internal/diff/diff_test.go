@@ -0,0 +1,98 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// lcs is the length of the longest common subsequence, by dynamic programming.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestLinesIsShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := random(), random()
+		lines := Lines(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, l := range lines {
+			if l.Kind != Insert {
+				gotA = append(gotA, l.Text)
+			}
+			if l.Kind != Delete {
+				gotB = append(gotB, l.Text)
+			}
+			if l.Kind != Equal {
+				edits++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("Lines(%q, %q) = %v does not turn one into the other", a, b, lines)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("Lines(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\n"
+	b := "one\n2\nthree\nfour\nfive\n"
+	want := `--- a
++++ b
+@@ -1,4 +1,5 @@
+ one
+-two
++2
+ three
+ four
++five
+`
+	if got := Unified(a, b, "a", "b", 3); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := Unified(a, a, "a", "b", 3); got != "" {
+		t.Errorf("equal texts: got %q", got)
+	}
+}
+
+func TestLinesUnrelatedTextsUseLittleMemory(t *testing.T) {
+	a := make([]string, 3000)
+	b := make([]string, 3000)
+	for i := range a {
+		a[i] = fmt.Sprint("a", i)
+		b[i] = fmt.Sprint("b", i)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	Lines(a, b)
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 10<<20 {
+		t.Errorf("allocated %d bytes", allocated)
+	}
+}
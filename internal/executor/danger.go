@@ -0,0 +1,358 @@
+package executor
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Check is a kind of risky command the analyzer looks for.
+type Check struct {
+	ID          string
+	Description string
+}
+
+// Checks lists what Analyze looks for. Their IDs are what a script's
+// allowlist holds.
+var Checks = []Check{
+	{"rm-rf", "rm -rf of a variable or root path"},
+	{"dd-device", "dd writing to a device"},
+	{"mkfs", "creating a filesystem"},
+	{"curl-pipe-shell", "downloaded script run by a shell"},
+	{"chmod-777", "recursive chmod 777"},
+	{"force-push", "git force push"},
+}
+
+// Finding is a risky command found in a script.
+type Finding struct {
+	Check   string
+	Line    int
+	Command string
+	Reason  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s [%s]\n    %s", f.Line, f.Reason, f.Check, f.Command)
+}
+
+var (
+	pipeToShell     = regexp.MustCompile(`\b(curl|wget)\b[^|;&]*\|\s*(sudo\s+(-\S+\s+)*)?(\S*/)?(ba|z|k|da|fi)?sh\b`)
+	shellOfDownload = regexp.MustCompile(`\b(sh|bash|zsh|ksh|dash|source|eval|\.)\s[^|;&]*(<\(|\$\()\s*(curl|wget)\b`)
+	rootPath        = regexp.MustCompile(`^(~|/[^/]*)/?\*?$`)
+	guardedVariable = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*:\?`)
+	singleQuoted    = regexp.MustCompile(`'[^']*'`)
+	safeDevices     = map[string]bool{"/dev/null": true, "/dev/zero": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true}
+)
+
+// prefixWords may come before the command itself
+var prefixWords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "while": true, "until": true, "do": true,
+	"!": true, "{": true, "time": true, "nohup": true, "exec": true, "command": true, "builtin": true,
+	"sudo": true, "doas": true, "env": true,
+}
+
+// Analyze looks for destructive commands in a script, after placeholders
+// have been substituted. It is a line based heuristic meant to catch
+// mistakes, not a shell parser: quoting and heredocs are not understood.
+func Analyze(script string) []Finding {
+	var findings []Finding
+
+	for _, line := range logicalLines(script) {
+		text := strings.TrimSpace(line.text)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if pipeToShell.MatchString(text) || shellOfDownload.MatchString(text) {
+			findings = append(findings, Finding{"curl-pipe-shell", line.number, text, "downloaded script run by a shell"})
+		}
+
+		for _, words := range splitCommands(text) {
+			args := commandArgs(words)
+			if len(args) == 0 {
+				continue
+			}
+			if check, reason := analyzeCommand(path.Base(args[0]), args[1:]); check != "" {
+				findings = append(findings, Finding{check, line.number, strings.Join(words, " "), reason})
+			}
+		}
+	}
+	return findings
+}
+
+// analyzeCommand checks a single command, returning the check it fails and
+// why, or "" if it looks safe.
+func analyzeCommand(name string, args []string) (string, string) {
+	switch {
+	case name == "rm":
+		flags, targets := splitFlags(args)
+		if !(hasFlag(flags, 'r', "--recursive") || hasFlag(flags, 'R', "--recursive")) || !hasFlag(flags, 'f', "--force") {
+			return "", ""
+		}
+		for _, target := range targets {
+			if isVariablePath(target) {
+				return "rm-rf", fmt.Sprintf("rm -rf of variable path %s", target)
+			}
+			if target := path.Clean(unquote(target)); rootPath.MatchString(target) {
+				return "rm-rf", fmt.Sprintf("rm -rf of %s", target)
+			}
+		}
+
+	case name == "dd":
+		for _, arg := range args {
+			if device, ok := strings.CutPrefix(unquote(arg), "of=/dev/"); ok && !safeDevices["/dev/"+device] && !strings.HasPrefix(device, "fd/") {
+				return "dd-device", "dd writing to /dev/" + device
+			}
+		}
+
+	case name == "mkfs" || strings.HasPrefix(name, "mkfs."):
+		return "mkfs", "creating a filesystem with " + name
+
+	case name == "chmod":
+		flags, operands := splitFlags(args)
+		if !hasFlag(flags, 'R', "--recursive") || len(operands) == 0 {
+			return "", ""
+		}
+		switch unquote(operands[0]) {
+		case "777", "0777", "a+rwx", "ugo+rwx":
+			return "chmod-777", "recursive chmod 777"
+		}
+
+	case name == "git":
+		for i, arg := range args {
+			if arg != "push" {
+				continue
+			}
+			flags, refspecs := splitFlags(args[i+1:])
+			if hasFlag(flags, 'f', "--force") {
+				return "force-push", "git push --force"
+			}
+			for _, refspec := range refspecs {
+				if strings.HasPrefix(unquote(refspec), "+") {
+					return "force-push", "git push of forced refspec " + refspec
+				}
+			}
+			break
+		}
+	}
+	return "", ""
+}
+
+// FilterAllowed drops findings whose check is in allowed, a list of check
+// IDs separated by spaces or commas.
+func FilterAllowed(findings []Finding, allowed string) []Finding {
+	var kept []Finding
+	for _, f := range findings {
+		if !IsAllowed(allowed, f.Check) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// ParseAllowlist validates a list of check IDs separated by spaces or
+// commas, returning it space separated.
+func ParseAllowlist(allowed string) (string, error) {
+	ids := splitList(allowed)
+	for _, id := range ids {
+		if !isCheck(id) {
+			return "", fmt.Errorf("unknown check '%s'", id)
+		}
+	}
+	return strings.Join(ids, " "), nil
+}
+
+// AllowChecks adds check IDs to an allowlist.
+func AllowChecks(allowed string, ids []string) string {
+	list := splitList(allowed)
+	for _, id := range ids {
+		if !contains(list, id) {
+			list = append(list, id)
+		}
+	}
+	return strings.Join(list, " ")
+}
+
+// RemoveChecks drops check IDs from an allowlist.
+func RemoveChecks(allowed string, ids []string) string {
+	var kept []string
+	for _, id := range splitList(allowed) {
+		if !contains(ids, id) {
+			kept = append(kept, id)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// IsAllowed reports whether an allowlist contains a check.
+func IsAllowed(allowed, id string) bool {
+	return contains(splitList(allowed), id)
+}
+
+func isCheck(id string) bool {
+	for _, check := range Checks {
+		if check.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+type logicalLine struct {
+	number int
+	text   string
+}
+
+// logicalLines joins backslash continued lines, numbering each by its
+// first line.
+func logicalLines(script string) []logicalLine {
+	var lines []logicalLine
+	var current strings.Builder
+	start := 0
+	for i, line := range strings.Split(script, "\n") {
+		if current.Len() == 0 {
+			start = i + 1
+		}
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\") + " ")
+			continue
+		}
+		current.WriteString(line)
+		lines = append(lines, logicalLine{start, current.String()})
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		lines = append(lines, logicalLine{start, current.String()})
+	}
+	return lines
+}
+
+// splitCommands splits a line into commands at ;, &, | and their doubled
+// forms, and each command into words at spaces, except inside quotes.
+// Quotes are kept in the words.
+func splitCommands(line string) [][]string {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	var quote rune
+
+	endWord := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			word.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			word.WriteRune(r)
+		case r == ';' || r == '&' || r == '|':
+			endCommand()
+		case r == ' ' || r == '\t':
+			endWord()
+		default:
+			word.WriteRune(r)
+		}
+	}
+	endCommand()
+	return commands
+}
+
+// commandArgs strips variable assignments and prefixes such as sudo from a
+// command, leaving the command name and its arguments.
+func commandArgs(fields []string) []string {
+	for len(fields) > 0 {
+		// Subshells and groups: (rm -rf /)
+		word := strings.TrimLeft(fields[0], "({")
+		if word == "" {
+			fields = fields[1:]
+			continue
+		}
+		fields[0] = word
+		switch {
+		case prefixWords[word]:
+			fields = fields[1:]
+			// Skip the prefix's own options, e.g. sudo -u root
+			for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+				fields = fields[1:]
+			}
+		case strings.Contains(word, "=") && !strings.HasPrefix(word, "="):
+			fields = fields[1:]
+		default:
+			return fields
+		}
+	}
+	return nil
+}
+
+// splitFlags separates options from operands, stopping at "--".
+func splitFlags(args []string) (flags, operands []string) {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return flags, append(operands, args[i+1:]...)
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			flags = append(flags, arg)
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	return flags, operands
+}
+
+// hasFlag reports whether a short flag appears, alone or grouped as in
+// -rf, or the long form is given exactly.
+func hasFlag(flags []string, short rune, long string) bool {
+	for _, flag := range flags {
+		if flag == long {
+			return true
+		}
+		if !strings.HasPrefix(flag, "--") && strings.ContainsRune(flag[1:], short) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVariablePath reports whether a path depends on a variable or command
+// substitution that isn't guarded with ${VAR:?}.
+func isVariablePath(arg string) bool {
+	arg = singleQuoted.ReplaceAllString(arg, "") // '$HOME' is literal
+	vars := strings.Count(arg, "$") + strings.Count(arg, "`")
+	return vars > len(guardedVariable.FindAllString(arg, -1))
+}
+
+// unquote drops the quotes from a word, e.g. '/'"$x" becomes /$x.
+func unquote(arg string) string {
+	return strings.NewReplacer(`'`, "", `"`, "").Replace(arg)
+}
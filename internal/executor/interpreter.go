@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DefaultInterpreter runs scripts whose language has no configured command.
+var DefaultInterpreter = []string{"bash"}
+
+var shells = map[string]bool{"bash": true, "sh": true, "zsh": true, "ksh": true, "dash": true}
+
+// fileInterpreters can't read their script from a pipe, so they get a
+// temporary file instead.
+var fileInterpreters = map[string]bool{
+	"node": true, "nodejs": true, "deno": true, "bun": true, "ts-node": true, "tsx": true,
+	"pwsh": true, "powershell": true,
+}
+
+// ResolveInterpreter picks the command a script is run with. A per-script
+// override wins, then the script's shebang line, then the interpreter
+// configured for its language, falling back to bash.
+func ResolveInterpreter(content, language, override string, interpreters map[string]string) []string {
+	if fields := strings.Fields(override); len(fields) > 0 {
+		return fields
+	}
+	if shebang := parseShebang(content); len(shebang) > 0 {
+		return shebang
+	}
+	if fields := strings.Fields(interpreters[strings.ToLower(language)]); len(fields) > 0 {
+		return fields
+	}
+	return DefaultInterpreter
+}
+
+// IsShell reports whether the interpreter is a POSIX-style shell, i.e.
+// whether placeholder values need shell quoting.
+func IsShell(interpreter []string) bool {
+	if len(interpreter) == 0 {
+		return true
+	}
+	return shells[interpreterName(interpreter)]
+}
+
+// needsScriptFile reports whether the interpreter must be given a file.
+func needsScriptFile(interpreter []string) bool {
+	return len(interpreter) > 0 && fileInterpreters[interpreterName(interpreter)]
+}
+
+// interpreterName is the program an interpreter command runs, looking
+// through env.
+func interpreterName(interpreter []string) string {
+	name := filepath.Base(interpreter[0])
+	if name == "env" {
+		for _, arg := range interpreter[1:] {
+			if !strings.HasPrefix(arg, "-") {
+				return filepath.Base(arg)
+			}
+		}
+	}
+	return name
+}
+
+// parseShebang splits a "#!" line the way the kernel does: the interpreter
+// path plus at most one argument. "#!/usr/bin/env -S" lines are split fully.
+func parseShebang(content string) []string {
+	if !strings.HasPrefix(content, "#!") {
+		return nil
+	}
+	line, _, _ := strings.Cut(content[2:], "\n")
+	line = strings.TrimSpace(line)
+
+	fields := strings.Fields(line)
+	if len(fields) > 2 && filepath.Base(fields[0]) == "env" && fields[1] == "-S" {
+		return append([]string{fields[0]}, fields[2:]...)
+	}
+	if len(fields) > 1 {
+		path := fields[0]
+		return []string{path, strings.TrimSpace(strings.TrimPrefix(line, path))}
+	}
+	return fields
+}
@@ -0,0 +1,168 @@
+package executor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mask replaces sensitive values in script output.
+const Mask = "****"
+
+// maskIdle is how long output that could be the start of a value is held
+// back before Mask is written in its place, so a script that pauses there,
+// say at a prompt, doesn't stall its output.
+const maskIdle = 200 * time.Millisecond
+
+// SensitiveValues returns the values of the sensitive placeholders, secrets
+// included, for Options.Mask.
+func SensitiveValues(placeholders []Placeholder, inputs map[string]string) []string {
+	var values []string
+	for _, ph := range placeholders {
+		if value := inputs[ph.Name]; ph.Sensitive && value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// MaskInputs returns the inputs with sensitive values, secrets included,
+// replaced by Mask, for showing a script without revealing them.
+func MaskInputs(placeholders []Placeholder, inputs map[string]string) map[string]string {
+	masked := make(map[string]string, len(inputs))
+	for name, value := range inputs {
+		masked[name] = value
+	}
+	for _, ph := range placeholders {
+		if _, ok := masked[ph.Name]; ok && ph.Sensitive {
+			masked[ph.Name] = Mask
+		}
+	}
+	return masked
+}
+
+// RedactInputs returns the inputs as they may be recorded: secrets left out
+// and other sensitive values masked.
+func RedactInputs(placeholders []Placeholder, inputs map[string]string) map[string]string {
+	redacted := WithoutSecrets(inputs)
+	for _, ph := range placeholders {
+		if _, ok := redacted[ph.Name]; ok && ph.Sensitive {
+			redacted[ph.Name] = Mask
+		}
+	}
+	return redacted
+}
+
+// MaskValues replaces the values in text with Mask.
+func MaskValues(text string, values []string) string {
+	var b strings.Builder
+	m := newMasker(values, func(s string) { b.WriteString(s) })
+	m.write(text)
+	m.flush()
+	return b.String()
+}
+
+// masker redacts values from a stream of output chunks. A chunk ending in
+// what could be the start of a value is held back until the next chunk
+// shows whether the value follows, so a value split across reads is still
+// caught. With idle set, held back output is shown as Mask after that long
+// without a new chunk; whatever the next chunks bring, it stays hidden.
+type masker struct {
+	values  []string
+	pending string
+	// hidden is how much of pending was already shown as Mask
+	hidden int
+	output  func(string)
+	idle    time.Duration
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+func newMasker(values []string, output func(string)) *masker {
+	m := &masker{output: output}
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		m.values = append(m.values, value)
+		// A PTY turns newlines into CRLF
+		if strings.Contains(value, "\n") {
+			m.values = append(m.values, strings.ReplaceAll(value, "\n", "\r\n"))
+		}
+	}
+	// Longest first, so a value containing another is masked whole
+	sort.Slice(m.values, func(i, j int) bool { return len(m.values[i]) > len(m.values[j]) })
+	return m
+}
+
+func (m *masker) write(chunk string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.pending += chunk
+
+	var b strings.Builder
+	i := 0
+scan:
+	for i < len(m.pending) {
+		rest := m.pending[i:]
+		for _, value := range m.values {
+			if strings.HasPrefix(rest, value) {
+				// The start of a hidden value was already masked
+				if i > 0 || m.hidden == 0 {
+					b.WriteString(Mask)
+				}
+				i += len(value)
+				continue scan
+			}
+		}
+		for _, value := range m.values {
+			if len(rest) < len(value) && strings.HasPrefix(value, rest) {
+				break scan // wait for the next chunk
+			}
+		}
+		if i >= m.hidden {
+			b.WriteByte(m.pending[i])
+		}
+		i++
+	}
+
+	m.pending = m.pending[i:]
+	m.hidden = max(m.hidden-i, 0)
+	if b.Len() > 0 {
+		m.output(b.String())
+	}
+	if m.pending != "" && m.idle > 0 {
+		m.timer = time.AfterFunc(m.idle, m.hide)
+	}
+}
+
+// hide shows output held back for a while as Mask, keeping it back in case
+// the rest of a value follows.
+func (m *masker) hide() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending != "" && m.hidden == 0 {
+		m.output(Mask)
+	}
+	m.hidden = len(m.pending)
+}
+
+// flush writes out anything held back and not yet masked, once the output
+// has ended.
+func (m *masker) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	if rest := m.pending[m.hidden:]; rest != "" {
+		m.output(rest)
+	}
+	m.pending = ""
+	m.hidden = 0
+}
@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaskerSplitValues(t *testing.T) {
+	var out strings.Builder
+	m := newMasker([]string{"hunter2", "ab"}, func(s string) { out.WriteString(s) })
+	for _, chunk := range []string{"pass=hun", "ter2 ab", " hunt", "er 2\n"} {
+		m.write(chunk)
+	}
+	m.flush()
+	if want := "pass=**** **** hunter 2\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestMaskerHidesHeldBackOutputWhenIdle(t *testing.T) {
+	tests := []struct {
+		name      string
+		before    string
+		after     []string
+		wantIdle  string
+		wantFinal string
+	}{
+		{"value completes", "password: hunt", []string{"er2 ok\n"}, "password: ****", "password: **** ok\n"},
+		{"value completes over several chunks", "password: h", []string{"unt", "er2\n"}, "password: ****", "password: ****\n"},
+		{"not a value after all", "hunt", []string{"ing\n"}, "****", "****ing\n"},
+		{"another value follows", "hun", []string{"hunter2\n"}, "****", "********\n"},
+		{"output ends", "hunt", nil, "****", "****"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var out strings.Builder
+			m := newMasker([]string{"hunter2"}, func(s string) {
+				mu.Lock()
+				defer mu.Unlock()
+				out.WriteString(s)
+			})
+			m.idle = 10 * time.Millisecond
+
+			m.write(tt.before)
+			time.Sleep(100 * time.Millisecond)
+			mu.Lock()
+			got := out.String()
+			mu.Unlock()
+			if got != tt.wantIdle {
+				t.Errorf("after idle: got %q, want %q", got, tt.wantIdle)
+			}
+
+			for _, chunk := range tt.after {
+				m.write(chunk)
+			}
+			m.flush()
+			if got := out.String(); got != tt.wantFinal {
+				t.Errorf("got %q, want %q", got, tt.wantFinal)
+			}
+		})
+	}
+}
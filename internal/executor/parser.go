@@ -1,33 +1,398 @@
 package executor
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
-var placeholderRegexp = regexp.MustCompile(`{{\s*([^}]+)\s*}}`)
+var (
+	placeholderRegexp = regexp.MustCompile(`{{\s*(.+?)\s*}}`)
+	// placeholderSpec is what a placeholder starts with: modifiers and a name
+	// followed by a type, a default or nothing. Any other {{...}}, such as a
+	// Go template passed to kubectl or helm, is left as it is.
+	placeholderSpec = regexp.MustCompile(`^((raw|sensitive):\s*)*(secret:\s*)?[A-Za-z_][A-Za-z0-9_-]*\s*([:=]|$)`)
+)
 
-// ParsePlaceholders returns all unique placeholder names found.
-func ParsePlaceholders(script string) []string {
-	matches := placeholderRegexp.FindAllStringSubmatch(script, -1)
-	unique := make(map[string]bool)
-	var placeholders []string
+// PlaceholderType describes which values a placeholder accepts.
+type PlaceholderType string
 
-	for _, match := range matches {
-		name := match[1]
-		if !unique[name] {
-			unique[name] = true
-			placeholders = append(placeholders, name)
+const (
+	TypeString PlaceholderType = "string"
+	TypeInt    PlaceholderType = "int"
+	TypeFloat  PlaceholderType = "float"
+	TypeBool   PlaceholderType = "bool"
+	TypeChoice PlaceholderType = "choice"
+	TypeRegex  PlaceholderType = "regex"
+)
+
+// Placeholder is a parsed {{...}} declaration, e.g. {{port:int=8080}},
+// {{env:choice(dev,staging,prod)}} or {{ip:regex(^\d+\.\d+\.\d+\.\d+$)}}.
+type Placeholder struct {
+	Name       string
+	Type       PlaceholderType
+	Default    string
+	HasDefault bool
+	Choices    []string
+	Pattern    *regexp.Regexp
+	// Sensitive values, {{sensitive:name}} and secrets, are masked in
+	// output and run history.
+	Sensitive bool
+	// Secret is set for {{secret:name}}, whose value comes from the secrets
+	// store. Its Name keeps the "secret:" prefix, so it never shares a value
+	// with a plain {{name}}.
+	Secret bool
+}
+
+// SecretName is the name of the secret a Secret placeholder refers to.
+func (p Placeholder) SecretName() string {
+	return strings.TrimPrefix(p.Name, secretPrefix)
+}
+
+// Validate reports whether value is acceptable for the placeholder.
+func (p Placeholder) Validate(value string) error {
+	switch p.Type {
+	case TypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s: %q is not an integer", p.Name, value)
 		}
+	case TypeFloat:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s: %q is not a number", p.Name, value)
+		}
+	case TypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s: %q is not a boolean", p.Name, value)
+		}
+	case TypeChoice:
+		for _, choice := range p.Choices {
+			if choice == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %q is not one of %s", p.Name, value, strings.Join(p.Choices, ", "))
+	case TypeRegex:
+		if !p.Pattern.MatchString(value) {
+			return fmt.Errorf("%s: %q does not match %s", p.Name, value, p.Pattern)
+		}
+	}
+	return nil
+}
+
+// Resolve applies the default to an empty value and validates the result.
+func (p Placeholder) Resolve(value string) (string, error) {
+	if value == "" && p.HasDefault {
+		value = p.Default
+	}
+	if err := p.Validate(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// Describe returns a short human readable hint such as "int, default 8080".
+func (p Placeholder) Describe() string {
+	var hints []string
+	switch p.Type {
+	case TypeChoice:
+		hints = append(hints, strings.Join(p.Choices, "/"))
+	case TypeRegex:
+		hints = append(hints, "matches "+p.Pattern.String())
+	case TypeString:
+	default:
+		hints = append(hints, string(p.Type))
+	}
+	if p.HasDefault {
+		hints = append(hints, "default "+p.Default)
 	}
+	return strings.Join(hints, ", ")
+}
+
+// ParsePlaceholders returns all unique placeholders found, in order of first
+// appearance. A bare {{name}} reuses the definition given elsewhere.
+func ParsePlaceholders(script string) ([]Placeholder, error) {
+	index := make(map[string]int)
+	var placeholders []Placeholder
+
+	for _, match := range findPlaceholders(script) {
+		ph, err := parsePlaceholder(script[match[2]:match[3]])
+		if err != nil {
+			return nil, err
+		}
 
+		i, exists := index[ph.Name]
+		if !exists {
+			index[ph.Name] = len(placeholders)
+			placeholders = append(placeholders, ph)
+			continue
+		}
+		// Upgrade a bare reference once the full definition turns up.
+		// Marking any use sensitive makes the placeholder sensitive.
+		sensitive := placeholders[i].Sensitive || ph.Sensitive
+		if isBare(placeholders[i]) && !isBare(ph) {
+			placeholders[i] = ph
+		}
+		placeholders[i].Sensitive = sensitive
+	}
+
+	return placeholders, nil
+}
+
+// templateKeywords close or continue a Go template action. Written bare in
+// a script that has other template actions, {{end}} belongs to the template.
+var templateKeywords = map[string]bool{"end": true, "else": true, "break": true, "continue": true}
+
+// findPlaceholders returns the submatch indexes of the placeholders in script.
+func findPlaceholders(script string) [][]int {
+	var matches [][]int
+	template := false
+	for _, match := range placeholderRegexp.FindAllStringSubmatchIndex(script, -1) {
+		if placeholderSpec.MatchString(script[match[2]:match[3]]) {
+			matches = append(matches, match)
+		} else {
+			template = true
+		}
+	}
+	if !template {
+		return matches
+	}
+
+	placeholders := matches[:0]
+	for _, match := range matches {
+		if !templateKeywords[script[match[2]:match[3]]] {
+			placeholders = append(placeholders, match)
+		}
+	}
 	return placeholders
 }
 
-// ReplacePlaceholders replaces placeholders with user inputs.
-func ReplacePlaceholders(script string, inputs map[string]string) string {
-	return placeholderRegexp.ReplaceAllStringFunc(script, func(match string) string {
-		nameMatch := placeholderRegexp.FindStringSubmatch(match)
-		if val, exists := inputs[nameMatch[1]]; exists {
-			return val
+// WithoutSecrets returns the inputs minus the values of secret placeholders,
+// for recording a run.
+func WithoutSecrets(inputs map[string]string) map[string]string {
+	public := make(map[string]string, len(inputs))
+	for name, value := range inputs {
+		if !strings.HasPrefix(name, secretPrefix) {
+			public[name] = value
+		}
+	}
+	return public
+}
+
+// ReplacePlaceholders replaces placeholders with user inputs. When quote is
+// set, values are escaped for the bash quoting context they appear in, so
+// they always stay a single literal; {{raw:name}} opts a single use out.
+// Values that can't be quoted safely where they appear are an error.
+func ReplacePlaceholders(script string, inputs map[string]string, quote bool) (string, error) {
+	result, _, err := SubstitutePlaceholders(script, inputs, quote)
+	return result, err
+}
+
+// Substitution is where a placeholder's value ended up in a script, as byte
+// offsets into the result of SubstitutePlaceholders.
+type Substitution struct {
+	Name       string
+	Start, End int
+}
+
+// SubstitutePlaceholders is ReplacePlaceholders, also returning where each
+// value was substituted.
+func SubstitutePlaceholders(script string, inputs map[string]string, quote bool) (string, []Substitution, error) {
+	var substitutions []Substitution
+	matches := findPlaceholders(script)
+	var quotings []quoting
+	var scanErr error
+	if quote {
+		quotings, scanErr = quoteContexts(script, matches)
+	}
+
+	var b strings.Builder
+	last := 0
+	for i, match := range matches {
+		b.WriteString(script[last:match[0]])
+		last = match[1]
+
+		mods, spec := splitModifiers(script[match[2]:match[3]])
+		name := placeholderName(spec)
+		val, exists := inputs[name]
+		if !exists {
+			b.WriteString(script[match[0]:match[1]])
+			continue
 		}
-		return match
-	})
+
+		start := b.Len()
+		if quote && !mods.raw {
+			if scanErr != nil {
+				return "", nil, fmt.Errorf("can't tell how {{%s}} is quoted: %w; use {{raw:%s}} to substitute it as is", name, scanErr, name)
+			}
+			quoted, err := quoteFor(quotings[i], val)
+			if err != nil {
+				return "", nil, fmt.Errorf("{{%s}}: %w", name, err)
+			}
+			b.WriteString(quoted)
+		} else {
+			b.WriteString(val)
+		}
+		substitutions = append(substitutions, Substitution{name, start, b.Len()})
+	}
+	b.WriteString(script[last:])
+
+	return b.String(), substitutions, nil
+}
+
+const (
+	modifierRaw       = "raw"
+	modifierSensitive = "sensitive"
+	secretPrefix      = "secret:"
+)
+
+// modifiers are the prefixes a placeholder spec may start with, in any
+// order: "raw:" turns quoting off for one use, "sensitive:" masks the value.
+type modifiers struct {
+	raw       bool
+	sensitive bool
+}
+
+// splitModifiers separates the leading modifiers from the placeholder spec.
+func splitModifiers(spec string) (modifiers, string) {
+	var mods modifiers
+	for {
+		if rest, ok := strings.CutPrefix(spec, modifierRaw+":"); ok {
+			mods.raw = true
+			spec = strings.TrimSpace(rest)
+		} else if rest, ok := strings.CutPrefix(spec, modifierSensitive+":"); ok {
+			mods.sensitive = true
+			spec = strings.TrimSpace(rest)
+		} else {
+			return mods, spec
+		}
+	}
+}
+
+func isBare(p Placeholder) bool {
+	return p.Type == TypeString && !p.HasDefault
+}
+
+// placeholderName extracts the name from a spec without validating the rest.
+// Secret names keep their "secret:" prefix.
+func placeholderName(spec string) string {
+	if name, ok := strings.CutPrefix(spec, secretPrefix); ok {
+		return secretPrefix + placeholderName(strings.TrimSpace(name))
+	}
+	if i := strings.IndexAny(spec, ":="); i >= 0 {
+		spec = spec[:i]
+	}
+	return strings.TrimSpace(spec)
+}
+
+// parsePlaceholder parses "[modifiers]name[:type][=default]" where type is
+// one of string, int, float, bool, choice(a,b,...) or regex(pattern), or
+// "[modifiers]secret:name".
+func parsePlaceholder(spec string) (Placeholder, error) {
+	mods, spec := splitModifiers(spec)
+	ph := Placeholder{Name: placeholderName(spec), Type: TypeString, Sensitive: mods.sensitive}
+	if ph.Name == "" || ph.Name == secretPrefix {
+		return ph, fmt.Errorf("placeholder {{%s}} has no name", spec)
+	}
+
+	if strings.HasPrefix(spec, secretPrefix) {
+		ph.Secret = true
+		ph.Sensitive = true
+		if rest := strings.TrimSpace(strings.TrimPrefix(spec, secretPrefix)); rest != ph.SecretName() {
+			return ph, fmt.Errorf("placeholder {{%s}}: secrets take no type or default", spec)
+		}
+		return ph, nil
+	}
+
+	var rest string
+	if i := strings.IndexAny(spec, ":="); i >= 0 {
+		rest = spec[i:]
+	}
+
+	if strings.HasPrefix(rest, ":") {
+		rest = strings.TrimSpace(rest[1:])
+		var err error
+		rest, err = parseType(&ph, rest)
+		if err != nil {
+			return ph, fmt.Errorf("placeholder {{%s}}: %w", spec, err)
+		}
+	}
+
+	if strings.HasPrefix(rest, "=") {
+		ph.Default = strings.TrimSpace(rest[1:])
+		ph.HasDefault = true
+		if err := ph.Validate(ph.Default); err != nil {
+			return ph, fmt.Errorf("placeholder {{%s}}: invalid default: %w", spec, err)
+		}
+	} else if rest != "" {
+		return ph, fmt.Errorf("placeholder {{%s}}: unexpected %q", spec, rest)
+	}
+
+	return ph, nil
+}
+
+// parseType fills in the type information and returns the unparsed remainder.
+func parseType(ph *Placeholder, spec string) (string, error) {
+	for _, t := range []PlaceholderType{TypeChoice, TypeRegex} {
+		prefix := string(t) + "("
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+		end := closingParen(spec, len(prefix))
+		if end < 0 {
+			return "", fmt.Errorf("unterminated %s(", t)
+		}
+		arg := spec[len(prefix):end]
+		ph.Type = t
+
+		if t == TypeChoice {
+			for _, choice := range strings.Split(arg, ",") {
+				if choice = strings.TrimSpace(choice); choice != "" {
+					ph.Choices = append(ph.Choices, choice)
+				}
+			}
+			if len(ph.Choices) == 0 {
+				return "", fmt.Errorf("choice() needs at least one option")
+			}
+		} else {
+			pattern, err := regexp.Compile(arg)
+			if err != nil {
+				return "", fmt.Errorf("invalid regex: %w", err)
+			}
+			ph.Pattern = pattern
+		}
+		return strings.TrimSpace(spec[end+1:]), nil
+	}
+
+	typeName := spec
+	if i := strings.Index(spec, "="); i >= 0 {
+		typeName = spec[:i]
+	}
+	switch PlaceholderType(strings.TrimSpace(typeName)) {
+	case TypeString, TypeInt, TypeFloat, TypeBool:
+		ph.Type = PlaceholderType(strings.TrimSpace(typeName))
+	default:
+		return "", fmt.Errorf("unknown type %q", strings.TrimSpace(typeName))
+	}
+	return strings.TrimSpace(spec[len(typeName):]), nil
+}
+
+// closingParen returns the index of the parenthesis closing the group opened
+// just before start, honouring nesting and backslash escapes.
+func closingParen(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
 }
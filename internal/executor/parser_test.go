@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePlaceholdersSkipsTemplates(t *testing.T) {
+	tests := []struct {
+		script string
+		names  []string
+	}{
+		{"echo {{name}}", []string{"name"}},
+		{"nc -l {{ port:int=8080 }} {{raw:sensitive:token}}", []string{"port", "token"}},
+		{"kubectl get pods -o go-template='{{range $i, $c := .items}}{{.metadata.name}}{{end}}' -n {{ns}}", []string{"ns"}},
+		{"helm template x --set a={{- .Values.a -}}", nil},
+		{"echo {{end}}", []string{"end"}},
+		{"echo {{secret:db}}", []string{"secret:db"}},
+	}
+	for _, tt := range tests {
+		placeholders, err := ParsePlaceholders(tt.script)
+		if err != nil {
+			t.Errorf("ParsePlaceholders(%q): %v", tt.script, err)
+			continue
+		}
+		var names []string
+		for _, ph := range placeholders {
+			names = append(names, ph.Name)
+		}
+		if !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("ParsePlaceholders(%q) = %q, want %q", tt.script, names, tt.names)
+		}
+	}
+}
+
+func TestReplacePlaceholdersKeepsTemplates(t *testing.T) {
+	script := `kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{end}}' -n {{ns}}`
+	want := `kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{end}}' -n kube-system`
+	got, err := ReplacePlaceholders(script, map[string]string{"ns": "kube-system", "end": "x"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,511 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var shellSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellQuote returns s as a single literal bash word.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if shellSafeRegexp.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// quoteContext is the bash quoting state a placeholder appears in.
+type quoteContext int
+
+const (
+	contextBare quoteContext = iota
+	contextSingle
+	contextDouble
+	contextANSI // $'...'
+	contextHeredoc
+	contextQuotedHeredoc
+	contextComment
+)
+
+// quoting is where a placeholder appears: the innermost quoting context, how
+// many backtick substitutions it is nested in and the heredoc it is in, if any.
+type quoting struct {
+	context   quoteContext
+	backticks int
+	heredoc   *heredoc
+	// braced is set inside "${...}", where a } would end the expansion
+	braced bool
+}
+
+// quoteFor escapes value so it stays a literal where it appears.
+func quoteFor(q quoting, value string) (string, error) {
+	var quoted string
+	switch q.context {
+	case contextSingle:
+		// Close the single quotes, splice in a quoted word and reopen them
+		quoted = "'" + ShellQuote(value) + "'"
+	case contextDouble:
+		special := "\\\"$`"
+		if q.braced {
+			special += "}"
+		}
+		quoted = escapeChars(value, special)
+	case contextANSI:
+		quoted = escapeChars(value, `\'`)
+	case contextHeredoc:
+		quoted = escapeChars(value, "\\$`")
+	case contextQuotedHeredoc:
+		quoted = value
+	case contextComment:
+		// A newline would end the comment and run the rest
+		quoted = strings.NewReplacer("\n", " ", "\r", " ").Replace(value)
+	default:
+		quoted = ShellQuote(value)
+	}
+	for range q.backticks {
+		// The body of `...` loses a level of backslashes before it is parsed
+		quoted = escapeChars(quoted, "\\$`")
+	}
+	if q.heredoc != nil {
+		return guardDelimiter(quoted, *q.heredoc, q.context == contextHeredoc)
+	}
+	return quoted, nil
+}
+
+// guardDelimiter stops a value from ending the heredoc it is in early. Bash
+// looks for the delimiter line before expanding anything, so no quoting
+// helps; in the text of an unquoted heredoc, an empty $() in front of the
+// line does.
+func guardDelimiter(quoted string, doc heredoc, expands bool) (string, error) {
+	lines := strings.Split(quoted, "\n")
+	for i, line := range lines {
+		if doc.stripTabs {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if !strings.HasPrefix(line, doc.delimiter) {
+			continue
+		}
+		if !expands {
+			return "", fmt.Errorf("the value has a line starting with %s, which would end the heredoc", doc.delimiter)
+		}
+		lines[i] = "$()" + lines[i]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func escapeChars(s, special string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type heredoc struct {
+	delimiter string
+	stripTabs bool
+	quoted    bool
+}
+
+// frame is a level of nesting in a script: a quoted string, a comment, a
+// heredoc body, or a $(...), `...` or ${...}, which have their own quoting
+// inside.
+type frame struct {
+	context quoteContext
+	// closer ends a substitution: ')' for $(...), '`' or '}' for ${...}
+	closer byte
+	// depth counts the parentheses or braces open in a $(...) or ${...}
+	depth int
+	// cases counts the case statements open in a $(...), whose patterns
+	// end in an unmatched ')'
+	cases int
+	doc   heredoc
+	// nested marks a `...` written as \`...\` inside another one
+	nested bool
+	// escapedQuotes is set for a `...` inside double quotes, where \" in the
+	// body stands for "
+	escapedQuotes bool
+}
+
+// scanner follows the quoting of a bash script one character at a time.
+type scanner struct {
+	script  string
+	i       int
+	stack   []frame
+	pending []heredoc
+}
+
+// quoteContexts scans a bash script and returns how each match is quoted.
+// The matched ranges themselves are treated as opaque. An error means the
+// script ended inside quotes or a substitution, so the scan can't be trusted.
+func quoteContexts(script string, matches [][]int) ([]quoting, error) {
+	s := scanner{script: script, stack: []frame{{context: contextBare}}}
+	quotings := make([]quoting, 0, len(matches))
+	m := 0
+
+	for s.i < len(script) {
+		if m < len(matches) && s.i >= matches[m][0] {
+			quotings = append(quotings, s.quoting())
+			s.i = max(s.i, matches[m][1])
+			m++
+			continue
+		}
+		s.step()
+	}
+
+	for len(quotings) < len(matches) {
+		quotings = append(quotings, s.quoting())
+	}
+	return quotings, s.unterminated()
+}
+
+// unterminated reports the quotes or substitution left open at the end.
+func (s *scanner) unterminated() error {
+	for _, f := range s.stack[1:] {
+		switch {
+		case f.closer == ')':
+			return errors.New("unterminated $(")
+		case f.closer == '`':
+			return errors.New("unterminated `")
+		case f.closer == '}':
+			return errors.New("unterminated ${")
+		case f.context == contextSingle:
+			return errors.New("unterminated '")
+		case f.context == contextDouble:
+			return errors.New(`unterminated "`)
+		case f.context == contextANSI:
+			return errors.New("unterminated $'")
+		}
+	}
+	return nil
+}
+
+func (s *scanner) top() *frame {
+	return &s.stack[len(s.stack)-1]
+}
+
+func (s *scanner) push(f frame) {
+	s.stack = append(s.stack, f)
+}
+
+func (s *scanner) pop() {
+	if len(s.stack) > 1 {
+		s.stack = s.stack[:len(s.stack)-1]
+	}
+}
+
+func (s *scanner) quoting() quoting {
+	q := quoting{context: s.top().context, braced: s.top().closer == '}'}
+	for _, f := range s.stack {
+		if f.closer == '`' {
+			q.backticks++
+		}
+		if f.context == contextHeredoc || f.context == contextQuotedHeredoc {
+			doc := f.doc
+			q.heredoc = &doc
+		}
+	}
+	return q
+}
+
+// backtick returns the innermost `...` being scanned, or nil.
+func (s *scanner) backtick() *frame {
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		if s.stack[i].closer == '`' {
+			return &s.stack[i]
+		}
+	}
+	return nil
+}
+
+// step scans the character at s.i and moves past it.
+func (s *scanner) step() {
+	c := s.script[s.i]
+	if bt := s.backtick(); bt != nil {
+		switch {
+		case c == '`':
+			// The first plain backtick ends the substitution, quoted or not
+			for len(s.stack) > 1 {
+				f := *s.top()
+				s.pop()
+				if f.closer == '`' && !f.nested {
+					break
+				}
+			}
+			s.i++
+			return
+		case c == '\\' && s.i+1 < len(s.script):
+			next := s.script[s.i+1]
+			if !strings.ContainsRune("$`\\", rune(next)) && !(next == '"' && bt.escapedQuotes) {
+				break
+			}
+			// \$, \`, \\ and \" stand for the plain character in the body
+			s.i++
+			c = next
+			if c == '`' {
+				if f := s.top(); f.closer == '`' && f.nested {
+					s.pop()
+				} else if f.context != contextSingle && f.context != contextANSI && f.context != contextComment {
+					s.push(frame{context: contextBare, closer: '`', nested: true})
+				}
+				s.i++
+				return
+			}
+		}
+	}
+
+	f := s.top()
+	switch f.context {
+	case contextSingle:
+		if c == '\'' {
+			s.pop()
+		}
+	case contextANSI:
+		switch c {
+		case '\\':
+			s.i++
+		case '\'':
+			s.pop()
+		}
+	case contextComment:
+		if c == '\n' {
+			// Leave the newline to the enclosing frame
+			s.pop()
+			return
+		}
+	case contextQuotedHeredoc:
+		if c == '\n' {
+			s.endHeredocLine()
+			return
+		}
+	case contextHeredoc:
+		if s.expansion(c) {
+			return
+		}
+		if c == '\n' {
+			s.endHeredocLine()
+			return
+		}
+	case contextDouble:
+		if s.expansion(c) {
+			return
+		}
+		switch {
+		case c == '"' && f.closer == 0:
+			s.pop()
+		case c == '"':
+			// Quotes inside "${...}" nest
+			s.push(frame{context: contextDouble})
+		case f.closer == '}':
+			s.brace(c)
+		}
+	default:
+		if s.expansion(c) || s.bare(c) {
+			return
+		}
+	}
+	s.i++
+}
+
+// expansion enters a $(...), ${...} or `...`, or skips an escaped character.
+// It reports whether it consumed c.
+func (s *scanner) expansion(c byte) bool {
+	f := s.top()
+	next := byte(0)
+	if s.i+1 < len(s.script) {
+		next = s.script[s.i+1]
+	}
+	switch {
+	case c == '\\':
+		s.i += 2
+	case c == '$' && next == '(':
+		s.push(frame{context: contextBare, closer: ')', depth: 1})
+		s.i += 2
+	case c == '$' && next == '{':
+		context := f.context
+		if context == contextHeredoc {
+			context = contextDouble
+		}
+		s.push(frame{context: context, closer: '}', depth: 1})
+		s.i += 2
+	case c == '`':
+		s.push(frame{context: contextBare, closer: '`', escapedQuotes: f.context == contextDouble})
+		s.i++
+	default:
+		return false
+	}
+	return true
+}
+
+// bare scans a character outside any quotes and reports whether it moved
+// past it itself.
+func (s *scanner) bare(c byte) bool {
+	f := s.top()
+	switch {
+	case c == '\'':
+		s.push(frame{context: contextSingle})
+	case c == '"':
+		s.push(frame{context: contextDouble})
+	case c == '$' && strings.HasPrefix(s.script[s.i:], "$'"):
+		s.push(frame{context: contextANSI})
+		s.i += 2
+		return true
+	case c == '#' && s.wordStart():
+		s.push(frame{context: contextComment})
+	case c == '<' && strings.HasPrefix(s.script[s.i:], "<<") && !strings.HasPrefix(s.script[s.i:], "<<<"):
+		var doc heredoc
+		if doc, s.i = parseHeredoc(s.script, s.i+2); doc.delimiter != "" {
+			s.pending = append(s.pending, doc)
+		}
+		return true
+	case c == '\n' && len(s.pending) > 0:
+		s.i++
+		s.nextHeredoc()
+		return true
+	case f.closer == ')' && c == '(':
+		f.depth++
+	case f.closer == ')' && c == ')':
+		if f.cases > 0 && f.depth == 1 {
+			// A case pattern, not the end of the substitution
+			break
+		}
+		if f.depth--; f.depth == 0 {
+			s.pop()
+		}
+	case f.closer == ')' && isLetter(c) && s.wordStart():
+		end := s.i
+		for end < len(s.script) && isLetter(s.script[end]) {
+			end++
+		}
+		switch s.script[s.i:end] {
+		case "case":
+			f.cases++
+		case "esac":
+			f.cases = max(f.cases-1, 0)
+		}
+		s.i = end
+		return true
+	case f.closer == '}':
+		s.brace(c)
+	}
+	return false
+}
+
+// brace tracks the braces inside a ${...}.
+func (s *scanner) brace(c byte) {
+	f := s.top()
+	switch c {
+	case '{':
+		f.depth++
+	case '}':
+		if f.depth--; f.depth == 0 {
+			s.pop()
+		}
+	}
+}
+
+// wordStart reports whether s.i starts a new shell word.
+func (s *scanner) wordStart() bool {
+	return s.i == 0 || strings.ContainsRune(" \t\n;&|()", rune(s.script[s.i-1]))
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// nextHeredoc starts the body of the next pending heredoc at s.i, skipping
+// any that are empty.
+func (s *scanner) nextHeredoc() {
+	for len(s.pending) > 0 {
+		doc := s.pending[0]
+		s.pending = s.pending[1:]
+		if end, ok := heredocEnd(s.script, s.i, doc); ok {
+			s.i = end
+			continue
+		}
+		context := contextHeredoc
+		if doc.quoted {
+			context = contextQuotedHeredoc
+		}
+		s.push(frame{context: context, doc: doc})
+		return
+	}
+}
+
+// endHeredocLine moves past the newline at s.i and ends the heredoc being
+// read if the next line is its delimiter.
+func (s *scanner) endHeredocLine() {
+	s.i++
+	if end, ok := heredocEnd(s.script, s.i, s.top().doc); ok {
+		s.i = end
+		s.pop()
+		s.nextHeredoc()
+	}
+}
+
+// heredocEnd reports whether the line starting at pos terminates the
+// heredoc, and if so returns the position after the delimiter line.
+func heredocEnd(script string, pos int, doc heredoc) (int, bool) {
+	end := strings.IndexByte(script[pos:], '\n')
+	if end < 0 {
+		end = len(script)
+	} else {
+		end += pos
+	}
+
+	line := script[pos:end]
+	if doc.stripTabs {
+		line = strings.TrimLeft(line, "\t")
+	}
+	if line != doc.delimiter {
+		return pos, false
+	}
+	if end < len(script) {
+		return end + 1, true
+	}
+	return end, true
+}
+
+// parseHeredoc reads the delimiter word following a "<<" operator.
+func parseHeredoc(script string, i int) (heredoc, int) {
+	var doc heredoc
+	if i < len(script) && script[i] == '-' {
+		doc.stripTabs = true
+		i++
+	}
+	for i < len(script) && (script[i] == ' ' || script[i] == '\t') {
+		i++
+	}
+
+	var word strings.Builder
+	for i < len(script) && !strings.ContainsRune(" \t\n;|&<>()", rune(script[i])) {
+		switch c := script[i]; c {
+		case '\'', '"':
+			doc.quoted = true
+			if end := strings.IndexByte(script[i+1:], c); end >= 0 {
+				word.WriteString(script[i+1 : i+1+end])
+				i += end + 2
+				continue
+			}
+		case '\\':
+			doc.quoted = true
+			i++
+			continue
+		default:
+			word.WriteByte(c)
+		}
+		i++
+	}
+
+	doc.delimiter = word.String()
+	return doc, i
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// hostileValues try to break out of each quoting context.
+var hostileValues = []string{
+	"plain",
+	"two  words",
+	"a; echo pwned",
+	"$(echo pwned)",
+	"`echo pwned`",
+	"'; echo pwned; '",
+	`"; echo pwned; "`,
+	`back\slash\`,
+	"x) echo pwned; (",
+	"} echo pwned {",
+	"line\necho pwned",
+	"EOF\necho pwned",
+	"\tEOF",
+}
+
+func TestQuoteForContexts(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+
+	// Running each script must print want with VALUE replaced by the value
+	tests := []struct {
+		name, script, want string
+	}{
+		{"bare", `printf '%s\n' {{x}}`, "VALUE"},
+		{"single", `printf '%s\n' 'pre {{x}} post'`, "pre VALUE post"},
+		{"double", `printf '%s\n' "pre {{x}} post"`, "pre VALUE post"},
+		{"ansi-c", `printf '%s\n' $'pre\t{{x}}\'s'`, "pre\tVALUE's"},
+		{"comment", "# {{x}}\nprintf '%s\\n' ok", "ok"},
+		{"heredoc", "cat <<EOF\npre {{x}}\nEOF", "pre VALUE"},
+		{"command substitution", `printf '%s\n' "$(printf '%s' {{x}})"`, "VALUE"},
+		{"nested command substitution", `printf '%s\n' "$(printf '%s' "$(printf '%s' {{x}})")"`, "VALUE"},
+		{"case in command substitution", `printf '%s\n' "$(case a in a) printf '%s' {{x}};; esac)"`, "VALUE"},
+		{"arithmetic before substitution", `printf '%s\n' "$(( 1 + 1 ))$(printf '%s' {{x}})"`, "2VALUE"},
+		{"backticks", "printf '%s\\n' `printf '%s' {{x}}`", "VALUE"},
+		{"backticks in double quotes", "printf '%s\\n' \"`printf '%s' {{x}}`\"", "VALUE"},
+		{"double quotes in backticks", "printf '%s\\n' \"`printf '%s' \\\"{{x}}\\\"`\"", "VALUE"},
+		{"parameter default", `printf '%s\n' "${unset_var:-{{x}}}"`, "VALUE"},
+		{"unquoted parameter default", `printf '%s\n' ${unset_var:-{{x}}}`, "VALUE"},
+		{"quoted parameter default", `printf '%s\n' "${unset_var:-"{{x}}"}"`, "VALUE"},
+		{"substitution in heredoc", "cat <<EOF\n$(printf '%s' {{x}})\nEOF", "VALUE"},
+	}
+	for _, tt := range tests {
+		for _, value := range hostileValues {
+			if strings.HasPrefix(tt.name, "bare") || strings.HasPrefix(tt.name, "unquoted") || tt.name == "backticks" {
+				// Unquoted substitutions are word split by printf
+				if strings.ContainsAny(value, " \t\n") {
+					continue
+				}
+			}
+			script, err := ReplacePlaceholders(tt.script, map[string]string{"x": value}, true)
+			if err != nil {
+				if strings.Contains(value, "EOF") && strings.Contains(tt.script, "<<") {
+					continue
+				}
+				t.Errorf("%s, %q: %v", tt.name, value, err)
+				continue
+			}
+			out, err := exec.Command("bash", "-c", script).CombinedOutput()
+			want := strings.ReplaceAll(tt.want, "VALUE", value) + "\n"
+			if err != nil || string(out) != want {
+				t.Errorf("%s, %q:\n%s\nprinted %q (%v), want %q", tt.name, value, script, out, err, want)
+			}
+		}
+	}
+}
+
+func TestQuoteForHeredocDelimiter(t *testing.T) {
+	tests := []struct {
+		script string
+		ok     bool
+	}{
+		// An empty $() keeps the line from ending an unquoted heredoc
+		{"cat <<EOF\n{{x}}\nEOF", true},
+		{"cat <<'EOF'\n{{x}}\nEOF", false},
+		{"cat <<EOF\n$(printf '%s' {{x}})\nEOF", false},
+		{"cat <<-EOF\n\t{{x}}\nEOF", true},
+	}
+	for _, tt := range tests {
+		_, err := ReplacePlaceholders(tt.script, map[string]string{"x": "a\nEOF\necho pwned"}, true)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: error %v, want ok %v", tt.script, err, tt.ok)
+		}
+	}
+}
+
+func TestQuoteContextsUnterminated(t *testing.T) {
+	for _, script := range []string{
+		`echo "$(echo {{x}}`,
+		"echo `echo {{x}}",
+		`echo '{{x}}`,
+		`echo $'{{x}}`,
+	} {
+		if _, err := ReplacePlaceholders(script, map[string]string{"x": "v"}, true); err == nil {
+			t.Errorf("%q: expected an error", script)
+		}
+	}
+	if _, err := ReplacePlaceholders(`echo "$(echo {{raw:x}}`, map[string]string{"x": "v"}, true); err != nil {
+		t.Errorf("raw placeholder: %v", err)
+	}
+}
@@ -1,45 +1,330 @@
 package executor
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/creack/pty"
 )
 
-func ExecuteScript(scriptContent string, outputHandler func(string)) error {
-	cmd := exec.Command("bash", "-c", scriptContent)
+const (
+	// killGrace is how long a cancelled script gets after SIGTERM before the
+	// whole process group is killed.
+	killGrace = 5 * time.Second
+	// outputGrace is how long to keep draining output after the script exits,
+	// in case background processes still hold the terminal or pipe open.
+	outputGrace = time.Second
+)
+
+// Options configures a script execution. The zero value runs the script
+// with bash, without a PTY, with no stdin, no timeout and output discarded.
+type Options struct {
+	// Interpreter is the command the script's path is passed to, see
+	// ResolveInterpreter. Empty means DefaultInterpreter.
+	Interpreter []string
+	// Name is what a shell script sees as $0. Empty means "bashhub".
+	Name    string
+	Timeout time.Duration
+	Dir     string
+	// Env entries (KEY=VALUE) are appended to bashhub's own environment
+	Env   []string
+	Stdin io.Reader
+	PTY   bool
+	// ForwardSignals relays SIGINT and SIGTERM received by bashhub to the
+	// script's process group while it runs.
+	ForwardSignals bool
+	Output         func(string)
+	// Mask lists values to replace with Mask in the output, even when a
+	// value is split across reads
+	Mask []string
+}
 
-	// Start the command with a pty. (pseudo terminal)
-	ptmx, err := pty.Start(cmd)
+// Result describes a finished execution.
+type Result struct {
+	ExitCode int
+	Duration time.Duration
+	// Killed is set when the script was stopped by cancellation or timeout
+	Killed   bool
+	TimedOut bool
+}
+
+// Run executes a script and waits for it to finish or for ctx to be done.
+// The interpreter reads the content from a pipe, so substituted secrets are
+// never written to disk. A non-zero exit is reported in Result.ExitCode, not
+// as an error; the error is reserved for scripts that could not be started.
+func Run(ctx context.Context, scriptContent string, opts Options) (Result, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = func(string) {}
+	}
+
+	interpreter := opts.Interpreter
+	if len(interpreter) == 0 {
+		interpreter = DefaultInterpreter
+	}
+	script, err := newScriptSource(scriptContent, interpreter)
 	if err != nil {
-		return err
+		return Result{ExitCode: -1}, err
+	}
+	defer script.close()
+
+	cmd := exec.CommandContext(ctx, interpreter[0], script.args(interpreter, opts.Name)...)
+	cmd.ExtraFiles = script.files()
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+
+	// Stop the whole process group, not just bash, when ctx is done
+	var killTimer *time.Timer
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		killTimer = time.AfterFunc(killGrace, func() {
+			syscall.Kill(pgid, syscall.SIGKILL)
+		})
+		return syscall.Kill(pgid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = killGrace
+
+	var reader *os.File
+	start := time.Now()
+
+	if opts.PTY {
+		// Start the command with a pty. (pseudo terminal) This also makes
+		// the child a session leader, so its pid is its process group.
+		ptmx, err := pty.Start(cmd)
+		if err != nil {
+			return Result{ExitCode: -1}, err
+		}
+		script.started()
+		reader = ptmx
+
+		// Copy user input to the command
+		if opts.Stdin != nil {
+			go io.Copy(ptmx, opts.Stdin)
+		}
+	} else {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return Result{ExitCode: -1}, err
+		}
+		cmd.Stdin = opts.Stdin
+		cmd.Stdout = w
+		cmd.Stderr = w
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+		err = cmd.Start()
+		w.Close()
+		if err != nil {
+			r.Close()
+			return Result{ExitCode: -1}, err
+		}
+		script.started()
+		reader = r
 	}
-	defer ptmx.Close()
+	defer reader.Close()
 
-	// Copy PTY output to outputHandler
+	copied := make(chan struct{})
 	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := ptmx.Read(buf)
-			if n > 0 {
-				outputHandler(string(buf[:n]))
-			}
-			if err != nil {
-				if err != io.EOF {
-					outputHandler(err.Error())
-				}
-				break
-			}
+		if len(opts.Mask) > 0 {
+			m := newMasker(opts.Mask, output)
+			m.idle = maskIdle
+			copyOutput(reader, m.write)
+			m.flush()
+		} else {
+			copyOutput(reader, output)
+		}
+		close(copied)
+	}()
+
+	if opts.ForwardSignals {
+		stop := forwardSignals(cmd.Process.Pid)
+		defer stop()
+	}
+
+	waitErr := cmd.Wait()
+	if killTimer != nil {
+		killTimer.Stop()
+	}
+
+	// Make sure the output handler is finished before returning
+	select {
+	case <-copied:
+	case <-time.After(outputGrace):
+		reader.Close()
+		<-copied
+	}
+
+	if cmd.ProcessState == nil {
+		return Result{ExitCode: -1, Duration: time.Since(start)}, waitErr
+	}
+
+	result := Result{
+		ExitCode: exitCode(cmd.ProcessState),
+		Duration: time.Since(start),
+		Killed:   ctx.Err() != nil && waitErr != nil,
+	}
+	result.TimedOut = result.Killed && errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	return result, nil
+}
+
+// scriptFD is the descriptor the interpreter reads a piped script from: the
+// first of cmd.ExtraFiles.
+const scriptFD = 3
+
+// scriptSource hands the script to the interpreter, through a pipe it opens
+// as /dev/fd/3 or, for interpreters that can't read a pipe, a temporary file
+// only the user can read.
+type scriptSource struct {
+	path    string
+	content string
+	r, w    *os.File
+}
+
+func newScriptSource(content string, interpreter []string) (*scriptSource, error) {
+	if needsScriptFile(interpreter) {
+		path, err := writeScriptFile(content)
+		if err != nil {
+			return nil, err
 		}
+		return &scriptSource{path: path}, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	return &scriptSource{path: fmt.Sprintf("/dev/fd/%d", scriptFD), content: content, r: r, w: w}, nil
+}
+
+// shellLoader reads the whole script from the pipe and closes it before
+// running anything, so $0 is the script's name rather than /dev/fd/3 and the
+// script's own children don't inherit the descriptor.
+const shellLoader = `__bashhub_script=$(cat <&3) && exec 3<&- && eval "unset __bashhub_script; $__bashhub_script"`
+
+// args are the interpreter's arguments after its command name.
+func (s *scriptSource) args(interpreter []string, name string) []string {
+	args := append([]string{}, interpreter[1:]...)
+	if s.r == nil || !IsShell(interpreter) {
+		return append(args, s.path)
+	}
+	if name == "" {
+		name = "bashhub"
+	}
+	return append(args, "-c", shellLoader, name)
+}
+
+// files are the descriptors the interpreter inherits.
+func (s *scriptSource) files() []*os.File {
+	if s.r == nil {
+		return nil
+	}
+	return []*os.File{s.r}
+}
+
+// started feeds the pipe once the interpreter holds its end. Writing in the
+// background lets scripts larger than the pipe buffer through.
+func (s *scriptSource) started() {
+	if s.w == nil {
+		return
+	}
+	s.r.Close()
+	go func() {
+		io.WriteString(s.w, s.content)
+		s.w.Close()
 	}()
+}
+
+// close removes the temporary file, or closes the pipe, which also stops a
+// write the interpreter never read.
+func (s *scriptSource) close() {
+	if s.w == nil {
+		os.Remove(s.path)
+		return
+	}
+	s.r.Close()
+	s.w.Close()
+}
+
+func writeScriptFile(content string) (string, error) {
+	f, err := os.CreateTemp("", "bashhub-run-*")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := f.Chmod(0o600); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// copyOutput streams everything read from r to the output handler.
+func copyOutput(r io.Reader, output func(string)) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			output(string(buf[:n]))
+		}
+		if err != nil {
+			// EIO is how Linux reports a PTY whose child has exited
+			if err != io.EOF && !errors.Is(err, syscall.EIO) && !errors.Is(err, os.ErrClosed) {
+				output(err.Error())
+			}
+			return
+		}
+	}
+}
+
+// forwardSignals relays SIGINT and SIGTERM to the child's process group until
+// stop is called, so bashhub outlives the script and can report its status.
+func forwardSignals(pid int) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
 
-	// Copy user input from terminal to the command
 	go func() {
-		io.Copy(ptmx, os.Stdin)
+		for {
+			select {
+			case sig := <-signals:
+				syscall.Kill(-pid, sig.(syscall.Signal))
+			case <-done:
+				return
+			}
+		}
 	}()
 
-	return cmd.Wait()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
 }
 
+// exitCode returns the exit status of a finished process, using the shell
+// convention of 128+N for a process killed by signal N.
+func exitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
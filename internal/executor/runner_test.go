@@ -0,0 +1,25 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunPipesScript(t *testing.T) {
+	// Larger than a pipe buffer, so the interpreter has to read while it
+	// is written
+	script := strings.Repeat("x=1\n", 50000) + `echo "$0 done"` + "\n" +
+		`[ -e /dev/fd/3 ] && echo "fd 3 leaked"` + "\n"
+	var output strings.Builder
+	result, err := Run(context.Background(), script, Options{
+		Name:   "deploy",
+		Output: func(s string) { output.WriteString(s) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "deploy done\n" {
+		t.Errorf("exit code %d, output %q", result.ExitCode, output.String())
+	}
+}
@@ -0,0 +1,115 @@
+// Package fuzzy scores fzf-style subsequence matches: "kbrst" matches
+// "kubectl-restart" because its letters appear in order.
+package fuzzy
+
+import (
+	"unicode"
+)
+
+// Scoring follows fzf: every matched character scores, starting a gap and
+// extending it costs, and matches at word starts or right after another
+// match earn a bonus. The first character's bonus counts double.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+	bonusBoundary     = scoreMatch / 2
+	bonusCamelCase    = bonusBoundary - 1
+	bonusConsecutive  = -(scoreGapStart + scoreGapExtension)
+	bonusFirstChar    = 2
+)
+
+// Match reports whether the characters of pattern appear in text in order,
+// ignoring case. The score rates how well: higher for matches at word starts,
+// in runs and close together. Positions are the rune indexes of text that
+// matched. An empty pattern matches with a score of 0.
+func Match(pattern, text string) (score int, positions []int, ok bool) {
+	p := []rune(pattern)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+	t := []rune(text)
+	for i := range p {
+		p[i] = unicode.ToLower(p[i])
+	}
+	lower := make([]rune, len(t))
+	for i, r := range t {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Find the first occurrence of the whole pattern, then walk back from
+	// its end to find the shortest window ending there
+	end, pi := -1, 0
+	for i, r := range lower {
+		if r == p[pi] {
+			pi++
+			if pi == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	start := end
+	for pi = len(p) - 1; start >= 0; start-- {
+		if lower[start] == p[pi] {
+			if pi--; pi < 0 {
+				break
+			}
+		}
+	}
+
+	// Score the window, matching greedily from its start
+	positions = make([]int, 0, len(p))
+	inGap, consecutive, firstBonus := false, 0, 0
+	pi = 0
+	for i := start; i <= end; i++ {
+		if pi < len(p) && lower[i] == p[pi] {
+			bonus := charBonus(t, i)
+			if consecutive > 0 {
+				// A run keeps the bonus of its first character
+				bonus = max(bonus, firstBonus, bonusConsecutive)
+			} else {
+				firstBonus = bonus
+			}
+			if pi == 0 {
+				bonus *= bonusFirstChar
+			}
+			score += scoreMatch + bonus
+			positions = append(positions, i)
+			inGap, consecutive = false, consecutive+1
+			pi++
+			continue
+		}
+		if inGap {
+			score += scoreGapExtension
+		} else {
+			score += scoreGapStart
+		}
+		inGap, consecutive = true, 0
+	}
+	return score, positions, true
+}
+
+// charBonus rates t[i] as a place for a match to land: the start of the
+// text or of a word, or a camelCase or letter-to-digit change.
+func charBonus(t []rune, i int) int {
+	if i == 0 {
+		return bonusBoundary
+	}
+	prev, cur := t[i-1], t[i]
+	switch {
+	case !isWord(prev) && isWord(cur):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur),
+		!unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		return bonusCamelCase
+	}
+	return 0
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,60 @@
+package fuzzy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		ok            bool
+		positions     []int
+	}{
+		{"", "anything", true, nil},
+		{"kbrst", "kubectl-restart", true, []int{0, 2, 8, 10, 11}},
+		{"KR", "kubectl-restart", true, []int{0, 8}},
+		{"db", "backup-db", true, []int{7, 8}},
+		{"xyz", "kubectl", false, nil},
+		{"aa", "a", false, nil},
+		{"é", "Café", true, []int{3}},
+	}
+	for _, tt := range tests {
+		_, positions, ok := Match(tt.pattern, tt.text)
+		if ok != tt.ok || !reflect.DeepEqual(positions, tt.positions) {
+			t.Errorf("Match(%q, %q) = %v, %v, want %v, %v", tt.pattern, tt.text, positions, ok, tt.positions, tt.ok)
+		}
+	}
+}
+
+func TestMatchScoresBetterMatchesHigher(t *testing.T) {
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		// Word starts beat the middle of words
+		{"db", "backup-db", "sandbox"},
+		// Consecutive beats scattered
+		{"rest", "restart", "r-e-s-t"},
+		// Close together beats far apart
+		{"ab", "a-b", "a-----b"},
+	}
+	for _, tt := range tests {
+		better, _, _ := Match(tt.pattern, tt.better)
+		worse, _, _ := Match(tt.pattern, tt.worse)
+		if better <= worse {
+			t.Errorf("Match(%q): %q scores %d, %q scores %d", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
+
+func TestMatchLongGapsStillMatch(t *testing.T) {
+	text := "d" + strings.Repeat("x", 68) + "b"
+	score, positions, ok := Match("db", text)
+	if !ok || len(positions) != 2 {
+		t.Fatalf("Match = %d, %v, %v", score, positions, ok)
+	}
+	if score >= 0 {
+		t.Errorf("score %d, expected the gap to make it negative", score)
+	}
+}
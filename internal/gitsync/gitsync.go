@@ -0,0 +1,652 @@
+// Package gitsync mirrors the script library to a git working tree, one
+// file per script as laid out by export-all, and exchanges it with the
+// repository's "origin" remote.
+//
+// The files as of the last sync are recorded in the database. Scripts
+// changed in bashhub are written out and committed; files changed by a pull
+// are read back in. When both sides changed the same file, git's merge
+// conflict is left in place until it is resolved with Resolve, and so is a
+// changed file that can't be read as a script.
+package gitsync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/scriptfile"
+)
+
+// settingPath stores the working tree the library is bound to
+const settingPath = "sync.path"
+
+const remoteName = "origin"
+
+// ErrNotConfigured is returned by Open before "bashhub sync init"
+var ErrNotConfigured = errors.New("sync is not set up, run 'bashhub sync init <path>' first")
+
+// ErrConflicts is returned while a pull is stopped on conflicting changes
+var ErrConflicts = errors.New("there are unresolved sync conflicts")
+
+// DetectLanguage names the language of a file read from the working tree
+type DetectLanguage func(fileName, content string) string
+
+// Repo is a library bound to a git working tree.
+type Repo struct {
+	Path   string
+	db     *sqlx.DB
+	detect DetectLanguage
+}
+
+// Report lists what a sync did. Script changes are by name, conflicts and
+// errors by path in the working tree.
+type Report struct {
+	Committed bool
+	Pushed    bool
+	// Added, Updated and Deleted are scripts changed in the library
+	Added, Updated, Deleted []string
+	Conflicts               []string
+	// Errors are files that could not be read as scripts
+	Errors []string
+	// Skipped are files Init left out as they don't look like scripts
+	Skipped []string
+}
+
+// Conflict is a file changed on both sides of a pull.
+type Conflict struct {
+	Path string
+	// Mine and Theirs are the two versions, empty for a side that deleted it
+	Mine, Theirs string
+}
+
+// Init binds the library to the git working tree at path. An existing
+// repository is used as is; otherwise one is cloned from remote, or created
+// with remote as its origin. Scripts already in the working tree are read
+// into the library, replacing scripts of the same name; other files, such as
+// a README, are left alone and listed in the report.
+func Init(db *sqlx.DB, path, remote string, detect DetectLanguage) (*Repo, Report, error) {
+	var report Report
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, report, err
+	}
+	r := &Repo{Path: path, db: db, detect: detect}
+
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		if remote != "" {
+			err = r.setRemote(remote)
+		}
+	} else if remote != "" && isEmptyDir(path) {
+		_, err = git("", "clone", "--quiet", remote, path)
+	} else if err = os.MkdirAll(path, 0o755); err == nil {
+		if _, err = r.git("init", "--quiet"); err == nil && remote != "" {
+			err = r.setRemote(remote)
+		}
+	}
+	if err != nil {
+		return nil, report, err
+	}
+
+	if err := database.SetSetting(db, settingPath, path); err != nil {
+		return nil, report, err
+	}
+	// Nothing has been synced with this working tree yet
+	if err := database.ReplaceSyncFiles(db, nil); err != nil {
+		return nil, report, err
+	}
+	read, err := r.readTree(&report)
+	if err != nil {
+		return nil, report, err
+	}
+	err = r.walk(nil, func(relPath string) {
+		report.Skipped = append(report.Skipped, relPath)
+	})
+	if err != nil {
+		return nil, report, err
+	}
+	if err := r.writeTree(read); err != nil {
+		return nil, report, err
+	}
+	report.Conflicts, err = r.unread()
+	return r, report, err
+}
+
+// Open returns the repository the library is bound to.
+func Open(db *sqlx.DB, detect DetectLanguage) (*Repo, error) {
+	path, err := database.GetSetting(db, settingPath)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, ErrNotConfigured
+	}
+	return &Repo{Path: path, db: db, detect: detect}, nil
+}
+
+// Remote returns the URL of the origin remote, or "" without one.
+func (r *Repo) Remote() string {
+	url, err := r.git("remote", "get-url", remoteName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(url)
+}
+
+// Push commits the library to the working tree and pushes it to the remote.
+func (r *Repo) Push() (Report, error) {
+	var report Report
+	if err := r.commitLibrary(&report); err != nil {
+		return report, err
+	}
+	if r.Remote() == "" {
+		return report, nil
+	}
+
+	branch, err := r.branch()
+	if err != nil {
+		return report, err
+	}
+	if _, err := r.git("push", "--quiet", "--set-upstream", remoteName, "HEAD:"+branch); err != nil {
+		if strings.Contains(err.Error(), "rejected") {
+			return report, errors.New("the remote has changes you don't have yet, run 'bashhub sync pull' first")
+		}
+		return report, err
+	}
+	report.Pushed = true
+	return report, nil
+}
+
+// Pull commits the library, merges the remote's changes into the working
+// tree and reads them into the library. If both sides changed a script it
+// returns ErrConflicts with the paths in the report.
+func (r *Repo) Pull() (Report, error) {
+	var report Report
+	if err := r.commitLibrary(&report); err != nil {
+		return report, err
+	}
+
+	if r.Remote() != "" {
+		branch, err := r.branch()
+		if err != nil {
+			return report, err
+		}
+		heads, err := r.git("ls-remote", "--heads", remoteName, branch)
+		if err != nil {
+			return report, err
+		}
+		// An empty remote has nothing to pull yet
+		if strings.TrimSpace(heads) != "" {
+			if _, err := r.git("pull", "--quiet", "--no-rebase", "--no-edit", remoteName, branch); err != nil {
+				conflicts, listErr := r.unmerged()
+				if listErr != nil || len(conflicts) == 0 {
+					return report, err
+				}
+				report.Conflicts = conflicts
+				return report, ErrConflicts
+			}
+		}
+	}
+
+	read, err := r.readTree(&report)
+	if err != nil {
+		return report, err
+	}
+	return report, r.commit("bashhub: normalise pulled scripts", read, &report)
+}
+
+// Conflicts lists the files a pull left conflicting, or once merged, the
+// files changed in the working tree that couldn't be read as scripts.
+func (r *Repo) Conflicts() ([]Conflict, error) {
+	paths, err := r.unmerged()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return r.unreadConflicts()
+	}
+	conflicts := make([]Conflict, 0, len(paths))
+	for _, path := range paths {
+		c := Conflict{Path: path}
+		// Stage 2 is our side of the merge, stage 3 theirs
+		c.Mine, _ = r.git("show", ":2:"+path)
+		c.Theirs, _ = r.git("show", ":3:"+path)
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, nil
+}
+
+// unreadConflicts pairs each unread file with the library's version of it.
+func (r *Repo) unreadConflicts() ([]Conflict, error) {
+	paths, err := r.unread()
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+	scripts, err := database.GetScripts(r.db)
+	if err != nil {
+		return nil, err
+	}
+	library := make(map[string]string, len(scripts))
+	for _, script := range scripts {
+		relPath, content := scriptfile.ScriptFile(script)
+		library[relPath] = content
+	}
+
+	conflicts := make([]Conflict, 0, len(paths))
+	for _, path := range paths {
+		theirs, err := os.ReadFile(filepath.Join(r.Path, filepath.FromSlash(path)))
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, Conflict{Path: path, Mine: library[path], Theirs: string(theirs)})
+	}
+	return conflicts, nil
+}
+
+// Resolve settles a conflict by keeping one side of it. Once none remain
+// the merge is committed and read into the library.
+func (r *Repo) Resolve(path string, keepMine bool) (Report, error) {
+	var report Report
+	conflicts, err := r.Conflicts()
+	if err != nil {
+		return report, err
+	}
+
+	var conflict *Conflict
+	for i := range conflicts {
+		if conflicts[i].Path == path {
+			conflict = &conflicts[i]
+		}
+	}
+	if conflict == nil {
+		return report, fmt.Errorf("%s has no conflict", path)
+	}
+	if merging, err := r.unmerged(); err != nil {
+		return report, err
+	} else if len(merging) == 0 {
+		return r.resolveUnread(path, keepMine)
+	}
+
+	content := conflict.Theirs
+	if keepMine {
+		content = conflict.Mine
+	}
+	if content == "" {
+		_, err = r.git("rm", "--quiet", "--", path)
+	} else if err = os.WriteFile(filepath.Join(r.Path, filepath.FromSlash(path)), []byte(content), scriptfile.FileMode(content)); err == nil {
+		_, err = r.git("add", "--", path)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	if report.Conflicts, err = r.unmerged(); err != nil || len(report.Conflicts) > 0 {
+		return report, err
+	}
+	if _, err := r.git("commit", "--quiet", "--no-edit"); err != nil {
+		return report, err
+	}
+	report.Committed = true
+
+	read, err := r.readTree(&report)
+	if err != nil {
+		return report, err
+	}
+	return report, r.commit("bashhub: normalise merged scripts", read, &report)
+}
+
+// resolveUnread settles a file that couldn't be read as a script: mine puts
+// the library's version back, theirs reads the file again once it's fixed.
+func (r *Repo) resolveUnread(path string, keepMine bool) (Report, error) {
+	var report Report
+	file := filepath.Join(r.Path, filepath.FromSlash(path))
+	read := make(map[string]bool)
+	if keepMine {
+		if err := os.Remove(file); err != nil {
+			return report, err
+		}
+	} else {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return report, err
+		}
+		previous, err := database.GetSyncFiles(r.db)
+		if err != nil {
+			return report, err
+		}
+		if err := r.readFile(path, data, previous[path].ScriptName, &report); err != nil {
+			return report, fmt.Errorf("%s: %w", path, err)
+		}
+		read[path] = true
+	}
+	return report, r.commit("bashhub: resolve "+path, read, &report)
+}
+
+// commitLibrary commits the library unless a merge is still unresolved.
+func (r *Repo) commitLibrary(report *Report) error {
+	conflicts, err := r.unmerged()
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		report.Conflicts = conflicts
+		return ErrConflicts
+	}
+	return r.commit("bashhub: update scripts", nil, report)
+}
+
+// commit writes the library to the working tree and commits any change.
+// It returns ErrConflicts after committing if files changed in the working
+// tree, other than those just read, had to be left unread.
+func (r *Repo) commit(message string, read map[string]bool, report *Report) error {
+	if err := r.writeTree(read); err != nil {
+		return err
+	}
+	if err := r.commitTree(message, report); err != nil {
+		return err
+	}
+	unread, err := r.unread()
+	if err != nil || len(unread) == 0 {
+		return err
+	}
+	report.Conflicts = unread
+	return ErrConflicts
+}
+
+func (r *Repo) commitTree(message string, report *Report) error {
+	if _, err := r.git("add", "--all"); err != nil {
+		return err
+	}
+	status, err := r.git("status", "--porcelain")
+	if err != nil || strings.TrimSpace(status) == "" {
+		return err
+	}
+	if _, err := r.git("commit", "--quiet", "-m", message); err != nil {
+		return err
+	}
+	report.Committed = true
+	return nil
+}
+
+// writeTree writes every script to its file, removes the files of scripts
+// deleted since the last sync, and records the result as synced. Files
+// changed since the last sync and not in read are left alone with their old
+// record, as writing would lose the change.
+func (r *Repo) writeTree(read map[string]bool) error {
+	scripts, err := database.GetScripts(r.db)
+	if err != nil {
+		return err
+	}
+	previous, err := database.GetSyncFiles(r.db)
+	if err != nil {
+		return err
+	}
+
+	files := make([]database.SyncFile, 0, len(scripts))
+	written := make(map[string]bool)
+	for _, script := range scripts {
+		relPath, content := scriptfile.ScriptFile(script)
+		path := filepath.Join(r.Path, filepath.FromSlash(relPath))
+		written[relPath] = true
+		if last, ok := previous[relPath]; !read[relPath] && r.changed(relPath, last, content) {
+			if ok {
+				files = append(files, last)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), scriptfile.FileMode(content)); err != nil {
+			return err
+		}
+		files = append(files, database.SyncFile{Path: relPath, ScriptName: script.Name, Hash: hash([]byte(content))})
+	}
+
+	// Files read in but now kept elsewhere, like one whose header names
+	// another category, are removed along with those of deleted scripts
+	stale := make(map[string]bool)
+	for relPath := range previous {
+		stale[relPath] = true
+	}
+	for relPath := range read {
+		stale[relPath] = true
+	}
+	for relPath := range stale {
+		if written[relPath] {
+			continue
+		}
+		if last := previous[relPath]; !read[relPath] && r.changed(relPath, last, "") {
+			files = append(files, last)
+			continue
+		}
+		err := os.Remove(filepath.Join(r.Path, filepath.FromSlash(relPath)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return database.ReplaceSyncFiles(r.db, files)
+}
+
+// changed reports whether the file at relPath differs from both its last
+// sync and content, which is about to be written there.
+func (r *Repo) changed(relPath string, last database.SyncFile, content string) bool {
+	data, err := os.ReadFile(filepath.Join(r.Path, filepath.FromSlash(relPath)))
+	if err != nil {
+		return false
+	}
+	sum := hash(data)
+	return sum != last.Hash && sum != hash([]byte(content))
+}
+
+// unread lists the files changed since the last sync that are not in the
+// library, such as a pulled file that couldn't be read as a script.
+func (r *Repo) unread() ([]string, error) {
+	previous, err := database.GetSyncFiles(r.db)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	err = r.walk(func(relPath string, data []byte) error {
+		if previous[relPath].Hash != hash(data) {
+			paths = append(paths, relPath)
+		}
+		return nil
+	}, nil)
+	return paths, err
+}
+
+// walk calls scripts with every script file in the working tree, and
+// others with the rest. Either may be nil.
+func (r *Repo) walk(scripts func(relPath string, data []byte) error, others func(relPath string)) error {
+	return filepath.WalkDir(r.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != r.Path && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(r.Path, path)
+		relPath := filepath.ToSlash(rel)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !scriptfile.IsScript(relPath, data):
+			if others != nil {
+				others(relPath)
+			}
+		case scripts != nil:
+			return scripts(relPath, data)
+		}
+		return nil
+	})
+}
+
+// readTree reads the files changed since the last sync into the library and
+// deletes the scripts whose files are gone. It returns the paths it read;
+// those it couldn't are listed in the report's errors.
+func (r *Repo) readTree(report *Report) (map[string]bool, error) {
+	previous, err := database.GetSyncFiles(r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	read := make(map[string]bool)
+	err = r.walk(func(relPath string, data []byte) error {
+		seen[relPath] = true
+		last, known := previous[relPath]
+		if known && last.Hash == hash(data) {
+			return nil
+		}
+
+		if err := r.readFile(relPath, data, last.ScriptName, report); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", relPath, err))
+			return nil
+		}
+		read[relPath] = true
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// A script moved to another file was updated, not deleted
+	moved := make(map[string]bool)
+	for _, name := range append(report.Added, report.Updated...) {
+		moved[name] = true
+	}
+	for relPath, last := range previous {
+		if seen[relPath] || moved[last.ScriptName] {
+			continue
+		}
+		script, err := database.GetScriptByName(r.db, last.ScriptName)
+		if err != nil {
+			continue // already gone
+		}
+		if err := database.DeleteScript(r.db, script.ID); err != nil {
+			return nil, err
+		}
+		report.Deleted = append(report.Deleted, script.Name)
+	}
+	return read, nil
+}
+
+// readFile saves a changed file as a script. lastName is the script the
+// file held at the last sync, if any.
+func (r *Repo) readFile(relPath string, data []byte, lastName string, report *Report) error {
+	script, _, err := scriptfile.ParseFile(relPath, data, "")
+	if err != nil {
+		return err
+	}
+	script.Language = r.detect(relPath, script.Content)
+
+	name := script.Name
+	if lastName != "" {
+		name = lastName
+	}
+	existing, err := database.GetScriptByName(r.db, name)
+	if err != nil {
+		if err := database.CreateScript(r.db, script); err != nil {
+			return err
+		}
+		report.Added = append(report.Added, script.Name)
+		return nil
+	}
+
+	script.ID = existing.ID
+	if err := database.UpdateScript(r.db, script); err != nil {
+		return err
+	}
+	report.Updated = append(report.Updated, script.Name)
+	return nil
+}
+
+func (r *Repo) setRemote(url string) error {
+	if _, err := r.git("remote", "get-url", remoteName); err == nil {
+		_, err = r.git("remote", "set-url", remoteName, url)
+		return err
+	}
+	_, err := r.git("remote", "add", remoteName, url)
+	return err
+}
+
+// branch is the checked out branch, which may not have commits yet
+func (r *Repo) branch() (string, error) {
+	branch, err := r.git("symbolic-ref", "--short", "HEAD")
+	return strings.TrimSpace(branch), err
+}
+
+// unmerged lists the paths with unresolved merge conflicts
+func (r *Repo) unmerged() ([]string, error) {
+	out, err := r.git("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+func (r *Repo) git(args ...string) (string, error) {
+	return git(r.Path, args...)
+}
+
+// git runs a git command in dir, or the current directory if empty, and
+// returns its output. Commits fall back to a $USER identity when git has
+// none configured.
+func git(dir string, args ...string) (string, error) {
+	var prefix []string
+	if dir != "" {
+		prefix = []string{"-C", dir}
+	}
+
+	cmd := exec.Command("git", append(prefix, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "LC_ALL=C")
+	if email, _ := exec.Command("git", append(prefix, "config", "user.email")...).Output(); len(bytes.TrimSpace(email)) == 0 {
+		name := os.Getenv("USER")
+		if name == "" {
+			name = "bashhub"
+		}
+		cmd.Env = append(cmd.Env,
+			"GIT_AUTHOR_NAME="+name, "GIT_AUTHOR_EMAIL="+name+"@localhost",
+			"GIT_COMMITTER_NAME="+name, "GIT_COMMITTER_EMAIL="+name+"@localhost")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func isEmptyDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	return errors.Is(err, fs.ErrNotExist) || (err == nil && len(entries) == 0)
+}
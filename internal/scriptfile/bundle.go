@@ -0,0 +1,170 @@
+package scriptfile
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maccalsa/bashhub/internal/database"
+)
+
+const (
+	bundleFormat  = "bashhub-bundle"
+	bundleVersion = 1
+	// bundleManifest is the name of the JSON bundle inside a .tar.gz bundle
+	bundleManifest = "bashhub-bundle.json"
+)
+
+// Bundle is a portable copy of a script library.
+type Bundle struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Scripts    []BundleScript `json:"scripts"`
+}
+
+// BundleScript is a script with its version history.
+type BundleScript struct {
+	database.Script
+	Versions []database.ScriptVersion `json:"versions"`
+}
+
+// NewBundle wraps scripts in a bundle stamped with the current time.
+func NewBundle(scripts []BundleScript) Bundle {
+	return Bundle{
+		Format:     bundleFormat,
+		Version:    bundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Scripts:    scripts,
+	}
+}
+
+// IsBundle reports whether path names a bundle rather than a directory.
+func IsBundle(path string) bool {
+	return strings.HasSuffix(path, ".json") || isTarGz(path)
+}
+
+func isTarGz(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// WriteBundle saves a bundle as JSON, or for a .tar.gz path as an archive
+// holding every script as a file, like an exported directory, plus the JSON.
+func WriteBundle(path string, bundle Bundle) error {
+	if !IsBundle(path) {
+		return fmt.Errorf("%s: bundles must end in .json or .tar.gz", path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isTarGz(path) {
+		err = writeTarGz(f, bundle)
+	} else {
+		err = writeJSON(f, bundle)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func writeJSON(w io.Writer, bundle Bundle) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bundle)
+}
+
+func writeTarGz(w io.Writer, bundle Bundle) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	add := func(name string, data []byte, mode int64) error {
+		err := archive.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    mode,
+			Size:    int64(len(data)),
+			ModTime: bundle.ExportedAt,
+		})
+		if err == nil {
+			_, err = archive.Write(data)
+		}
+		return err
+	}
+
+	var manifest strings.Builder
+	if err := writeJSON(&manifest, bundle); err != nil {
+		return err
+	}
+	if err := add(bundleManifest, []byte(manifest.String()), 0o644); err != nil {
+		return err
+	}
+	for _, script := range bundle.Scripts {
+		name, content := ScriptFile(script.Script)
+		if err := add(name, []byte(content), int64(FileMode(content))); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadBundle loads a bundle written by WriteBundle.
+func ReadBundle(path string) (Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Bundle{}, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if isTarGz(path) {
+		if r, err = manifestReader(f); err != nil {
+			return Bundle{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	var bundle Bundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return Bundle{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if bundle.Format != bundleFormat {
+		return Bundle{}, fmt.Errorf("%s is not a bashhub bundle", path)
+	}
+	if bundle.Version > bundleVersion {
+		return Bundle{}, fmt.Errorf("%s: bundle version %d is newer than this bashhub supports", path, bundle.Version)
+	}
+	return bundle, nil
+}
+
+// manifestReader finds the JSON bundle in a .tar.gz bundle.
+func manifestReader(r io.Reader) (io.Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("archive has no %s", bundleManifest)
+		} else if err != nil {
+			return nil, err
+		}
+		if header.Name == bundleManifest {
+			return archive, nil
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package scriptfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
+)
+
+// doubleSlashComments are languages whose line comments start with //
+var doubleSlashComments = map[string]bool{
+	"javascript": true, "typescript": true, "go": true, "rust": true,
+	"c": true, "c++": true, "java": true, "kotlin": true, "swift": true,
+	"scala": true, "groovy": true, "dart": true,
+}
+
+// doubleDashComments are languages whose line comments start with --
+var doubleDashComments = map[string]bool{
+	"lua": true, "sql": true, "haskell": true,
+}
+
+// scriptExtensions are the extensions of the script languages bashhub runs
+var scriptExtensions = map[string]bool{
+	".sh": true, ".bash": true, ".zsh": true, ".fish": true, ".tcsh": true,
+	".py": true, ".js": true, ".mjs": true, ".ts": true, ".rb": true,
+	".pl": true, ".php": true, ".ps1": true, ".lua": true, ".awk": true,
+	".jq": true,
+}
+
+// CommentPrefix returns the line comment marker used for the header of a
+// script in language.
+func CommentPrefix(language string) string {
+	language = strings.ToLower(language)
+	switch {
+	case doubleSlashComments[language]:
+		return "//"
+	case doubleDashComments[language]:
+		return "--"
+	}
+	return "#"
+}
+
+// Extension returns the usual file extension for language, taken from the
+// file patterns of its chroma lexer, or ".txt" if there is none.
+func Extension(language string) string {
+	if lexer := lexers.Get(language); lexer != nil {
+		for _, pattern := range lexer.Config().Filenames {
+			if ext := path.Ext(pattern); strings.HasPrefix(pattern, "*.") && len(ext) > 1 && !strings.ContainsAny(ext, "*?[") {
+				return ext
+			}
+		}
+	}
+	return ".txt"
+}
+
+// FileName returns the file name for a script, with the extension of its
+// language.
+func FileName(name, language string) string {
+	return name + Extension(language)
+}
+
+// CategoryDir turns a category into a relative slash-separated directory.
+// Empty, "." and ".." elements are dropped so the result stays inside the
+// export directory.
+func CategoryDir(category string) string {
+	var parts []string
+	for _, part := range strings.Split(category, "/") {
+		part = strings.TrimSpace(part)
+		if part != "" && part != "." && part != ".." {
+			parts = append(parts, strings.ReplaceAll(part, `\`, "_"))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Render writes header as "bashhub:" comments at the top of content, after
+// the shebang if there is one: every field with a value, and those in
+// header.Keys even when empty. ParseHeader reads them back.
+func Render(header Header, language, content string) string {
+	prefix := CommentPrefix(language) + " " + headerMarker + " "
+	single := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+	var b strings.Builder
+	if strings.HasPrefix(content, "#!") {
+		shebang, rest, _ := strings.Cut(content, "\n")
+		b.WriteString(shebang + "\n")
+		content = rest
+	}
+
+	for _, field := range []struct{ key, value string }{
+		{"name", header.Name},
+		{"description", header.Description},
+		{"category", header.Category},
+		{"tags", strings.Join(header.Tags, " ")},
+		{"interpreter", header.Interpreter},
+		{"allow", strings.Join(header.Allow, " ")},
+	} {
+		if field.value != "" || header.Keys[field.key] {
+			b.WriteString(prefix + field.key + "=" + single.Replace(field.value) + "\n")
+		}
+	}
+	if header.RawPlaceholders {
+		b.WriteString(prefix + "raw_placeholders=true\n")
+	}
+
+	b.WriteString(content)
+	return b.String()
+}
+
+// ScriptFile returns the relative path and content of the file a script is
+// exported to: <category>/<name><ext>, with a header importing it back.
+// The description and category are always written, so empty ones don't
+// come back as an importer's defaults.
+func ScriptFile(script database.Script) (string, string) {
+	header := Header{
+		Description:     script.Description,
+		Category:        script.Category,
+		Interpreter:     script.Interpreter,
+		RawPlaceholders: script.RawPlaceholders,
+		Tags:            script.Tags,
+		Allow:           strings.Fields(script.AllowedChecks),
+		Keys:            map[string]bool{"description": true, "category": true},
+	}
+	dir := CategoryDir(script.Category)
+	return path.Join(dir, FileName(script.Name, script.Language)), Render(header, script.Language, script.Content)
+}
+
+// FileMode is the permission for an exported script file: executable when
+// it has a shebang.
+func FileMode(content string) os.FileMode {
+	if strings.HasPrefix(content, "#!") {
+		return 0o755
+	}
+	return 0o644
+}
+
+// IsScript reports whether a file in a directory of scripts holds one: it
+// has a bashhub header or a shebang, or a script language's extension.
+// Other files, like a README, are not scripts.
+func IsScript(relPath string, data []byte) bool {
+	if scriptExtensions[strings.ToLower(path.Ext(relPath))] || bytes.HasPrefix(data, []byte("#!")) {
+		return true
+	}
+	// A header that fails to parse still marks a script
+	header, _, err := ParseHeader(string(data))
+	return err != nil || len(header.Keys) > 0
+}
+
+// ParseFile builds a script from a file at the slash-separated relPath: its
+// name from the file name, its category from the directory, or
+// defaultCategory at the top, unless the header says otherwise. Language is
+// left for the caller to detect. The header is returned so callers can tell
+// which fields the file sets.
+func ParseFile(relPath string, data []byte, defaultCategory string) (database.Script, Header, error) {
+	if !utf8.Valid(data) || strings.ContainsRune(string(data), 0) {
+		return database.Script{}, Header{}, errors.New("not a text file")
+	}
+
+	header, content, err := ParseHeader(string(data))
+	if err != nil {
+		return database.Script{}, header, err
+	}
+	if strings.TrimSpace(content) == "" {
+		return database.Script{}, header, errors.New("file is empty")
+	}
+
+	fileName := path.Base(relPath)
+	script := database.Script{
+		Name:            strings.TrimSuffix(fileName, path.Ext(fileName)),
+		Description:     header.Description,
+		Content:         content,
+		Category:        defaultCategory,
+		RawPlaceholders: header.RawPlaceholders,
+		Interpreter:     header.Interpreter,
+	}
+	if script.Tags, err = database.NormalizeTags(header.Tags); err != nil {
+		return script, header, fmt.Errorf("tags: %w", err)
+	}
+	if script.AllowedChecks, err = executor.ParseAllowlist(strings.Join(header.Allow, " ")); err != nil {
+		return script, header, fmt.Errorf("allow: %w", err)
+	}
+	if dir := path.Dir(relPath); dir != "." {
+		script.Category = dir
+	}
+	if header.Name != "" {
+		script.Name = header.Name
+	}
+	if header.Keys["category"] {
+		script.Category = header.Category
+	}
+
+	return script, header, database.ValidateName(script.Name)
+}
+
+// Merge updates existing from a script parsed from a file: the content,
+// language, name and category, which every file has, and the other fields
+// only when its header sets them.
+func Merge(existing, parsed database.Script, header Header) database.Script {
+	merged := existing
+	merged.Name = parsed.Name
+	merged.Content = parsed.Content
+	merged.Language = parsed.Language
+	merged.Category = parsed.Category
+	if header.Keys["description"] {
+		merged.Description = parsed.Description
+	}
+	if header.Keys["tags"] {
+		merged.Tags = parsed.Tags
+	}
+	if header.Keys["interpreter"] {
+		merged.Interpreter = parsed.Interpreter
+	}
+	if header.Keys["allow"] {
+		merged.AllowedChecks = parsed.AllowedChecks
+	}
+	if header.Keys["raw_placeholders"] {
+		merged.RawPlaceholders = parsed.RawPlaceholders
+	}
+	return merged
+}
@@ -0,0 +1,63 @@
+package scriptfile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maccalsa/bashhub/internal/database"
+)
+
+func TestMergeKeepsFieldsTheHeaderLacks(t *testing.T) {
+	existing := database.Script{
+		ID:            7,
+		Name:          "deploy",
+		Description:   "Deploy it",
+		Content:       "echo v1\n",
+		Category:      "ops",
+		Language:      "bash",
+		Interpreter:   "bash -e",
+		AllowedChecks: "rm-rf",
+		Tags:          []string{"prod"},
+	}
+	parsed, header, err := ParseFile("ops/deploy.sh", []byte("# bashhub: tags=new, description=\necho v2\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.Language = "bash"
+
+	want := existing
+	want.Content = "echo v2\n"
+	want.Tags = []string{"new"}
+	want.Description = ""
+	if got := Merge(existing, parsed, header); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %+v, want %+v", got, want)
+	}
+}
+
+func TestScriptFileRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		script database.Script
+	}{
+		{"empty description and category", database.Script{Name: "a", Content: "echo a\n"}},
+		{"general category", database.Script{Name: "b", Category: "General", Content: "echo b\n"}},
+		{"nested category", database.Script{Name: "c", Category: "AWS/EC2", Description: "Lists, then stops", Content: "#!/bin/bash\necho c\n"}},
+		{"category outside the export directory", database.Script{Name: "d", Category: "../ops", Content: "echo d\n"}},
+		{"every field", database.Script{
+			Name: "e", Category: "ops", Description: "All of it", Content: "echo e\n",
+			Interpreter: "bash -e", RawPlaceholders: true, AllowedChecks: "rm-rf", Tags: []string{"prod"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relPath, content := ScriptFile(tt.script)
+			got, _, err := ParseFile(relPath, []byte(content), "Imported")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.script) {
+				t.Errorf("%s round-tripped to %+v, want %+v", relPath, got, tt.script)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Package scriptfile converts between stored scripts and files on disk,
+// whose metadata lives in "bashhub:" header comments such as
+//
+//	# bashhub: description=Nightly backup, category=ops/db, tags=backup cron
+package scriptfile
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Header is the metadata found in a file's "bashhub:" comments. Empty
+// fields were not set.
+type Header struct {
+	Name            string
+	Description     string
+	Category        string
+	Interpreter     string
+	Tags            []string
+	RawPlaceholders bool
+	// Allow lists the dangerous-command checks the script may trigger
+	Allow []string
+	// Keys holds every key the header sets, including those set to an
+	// empty value
+	Keys map[string]bool
+}
+
+const headerMarker = "bashhub:"
+
+// commentPrefixes start a line comment in the languages bashhub runs
+var commentPrefixes = []string{"#", "//", "--", ";"}
+
+var headerKeys = map[string]bool{
+	"name":             true,
+	"description":      true,
+	"category":         true,
+	"interpreter":      true,
+	"tags":             true,
+	"raw_placeholders": true,
+	"allow":            true,
+}
+
+var keyPattern = regexp.MustCompile(`^\s*([a-z_]+)\s*=`)
+
+// ParseHeader reads the "bashhub:" comments at the top of a file, before the
+// first line of code, and returns them with the content minus those lines.
+// Other comments and the shebang are left in place.
+func ParseHeader(content string) (Header, string, error) {
+	var header Header
+	lines := strings.SplitAfter(content, "\n")
+	kept := make([]string, 0, len(lines))
+
+	inHeader := true
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inHeader && !(i == 0 && strings.HasPrefix(trimmed, "#!")) {
+			prefix := commentPrefix(trimmed)
+			switch {
+			case trimmed == "":
+			case prefix == "":
+				inHeader = false
+			default:
+				comment := strings.TrimSpace(strings.TrimPrefix(trimmed, prefix))
+				if fields, ok := strings.CutPrefix(comment, headerMarker); ok {
+					if err := header.parseFields(fields); err != nil {
+						return Header{}, content, fmt.Errorf("line %d: %w", i+1, err)
+					}
+					continue
+				}
+			}
+		}
+		kept = append(kept, line)
+	}
+
+	return header, strings.Join(kept, ""), nil
+}
+
+// parseFields reads comma separated key=value pairs. A comma not followed by
+// a known key belongs to the value, so descriptions may contain commas.
+func (h *Header) parseFields(fields string) error {
+	var key string
+	values := make(map[string]string)
+	for _, part := range strings.Split(fields, ",") {
+		if m := keyPattern.FindStringSubmatch(part); m != nil && (headerKeys[m[1]] || key == "") {
+			key = m[1]
+			if !headerKeys[key] {
+				return fmt.Errorf("unknown header key '%s'", key)
+			}
+			values[key] = part[len(m[0]):]
+			continue
+		}
+		if key == "" {
+			return fmt.Errorf("expected key=value, got '%s'", strings.TrimSpace(part))
+		}
+		values[key] += "," + part
+	}
+
+	if h.Keys == nil {
+		h.Keys = make(map[string]bool)
+	}
+	for key, value := range values {
+		h.Keys[key] = true
+		value = strings.TrimSpace(value)
+		switch key {
+		case "name":
+			h.Name = value
+		case "description":
+			h.Description = value
+		case "category":
+			h.Category = value
+		case "interpreter":
+			h.Interpreter = value
+		case "tags":
+			h.Tags = SplitTags(value)
+		case "allow":
+			h.Allow = SplitTags(value)
+		case "raw_placeholders":
+			raw, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("raw_placeholders: '%s' is not true or false", value)
+			}
+			h.RawPlaceholders = raw
+		}
+	}
+	return nil
+}
+
+// SplitTags splits a list of tags separated by commas or spaces.
+func SplitTags(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+func commentPrefix(line string) string {
+	for _, prefix := range commentPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
@@ -0,0 +1,243 @@
+// Package secrets keeps secret values encrypted in the database and fills in
+// {{secret:name}} placeholders at run time.
+//
+// Values are sealed with AES-256-GCM under a key derived with Argon2id from a
+// passphrase or the contents of a key file. The salt and a check value, which
+// tells a wrong passphrase apart from a corrupt secret, live in the settings
+// table; the key itself is never stored.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
+	"golang.org/x/crypto/argon2"
+)
+
+// Environment variables supplying the key, see KeyFromEnv
+const (
+	EnvKeyFile    = "BASHHUB_SECRETS_KEY_FILE"
+	EnvPassphrase = "BASHHUB_SECRETS_PASSPHRASE"
+)
+
+// Settings holding the key derivation salt and the check value
+const (
+	saltSetting  = "secrets.salt"
+	checkSetting = "secrets.check"
+	checkText    = "bashhub secrets"
+)
+
+// Argon2id parameters, as recommended by RFC 9106 for constrained memory
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	keyLength    = 32
+	saltLength   = 16
+)
+
+var (
+	ErrNotFound = errors.New("secret not found")
+	ErrWrongKey = errors.New("wrong secrets passphrase or key file")
+	ErrNoKey    = fmt.Errorf("no secrets key; set %s or %s", EnvPassphrase, EnvKeyFile)
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// ValidateName checks a secret name can be used in a {{secret:name}}
+// placeholder.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name '%s': use letters, digits, '_', '.' and '-', starting with a letter or '_'", name)
+	}
+	return nil
+}
+
+// Store encrypts and decrypts secrets with an unlocked key.
+type Store struct {
+	db   *sqlx.DB
+	aead cipher.AEAD
+}
+
+// Initialized reports whether a key has been set up, i.e. whether the next
+// Unlock checks the key rather than adopting it.
+func Initialized(db *sqlx.DB) (bool, error) {
+	salt, err := database.GetSetting(db, saltSetting)
+	return salt != "", err
+}
+
+// Unlock derives the key from a passphrase or key file contents. The first
+// unlock of a database adopts the key; later ones return ErrWrongKey for any
+// other key.
+func Unlock(db *sqlx.DB, keyMaterial []byte) (*Store, error) {
+	if len(keyMaterial) == 0 {
+		return nil, ErrNoKey
+	}
+
+	encodedSalt, err := database.GetSetting(db, saltSetting)
+	if err != nil {
+		return nil, err
+	}
+	fresh := encodedSalt == ""
+
+	var salt []byte
+	if fresh {
+		salt = make([]byte, saltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	} else if salt, err = base64.StdEncoding.DecodeString(encodedSalt); err != nil {
+		return nil, fmt.Errorf("invalid %s setting: %w", saltSetting, err)
+	}
+
+	key := argon2.IDKey(keyMaterial, salt, argonTime, argonMemory, argonThreads, keyLength)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	store := &Store{db: db, aead: aead}
+
+	if fresh {
+		check, err := store.seal(checkSetting, []byte(checkText))
+		if err != nil {
+			return nil, err
+		}
+		if err := database.SetSetting(db, checkSetting, base64.StdEncoding.EncodeToString(check)); err != nil {
+			return nil, err
+		}
+		return store, database.SetSetting(db, saltSetting, base64.StdEncoding.EncodeToString(salt))
+	}
+
+	encodedCheck, err := database.GetSetting(db, checkSetting)
+	if err != nil {
+		return nil, err
+	}
+	check, err := base64.StdEncoding.DecodeString(encodedCheck)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s setting: %w", checkSetting, err)
+	}
+	if _, err := store.open(checkSetting, check); err != nil {
+		return nil, ErrWrongKey
+	}
+	return store, nil
+}
+
+// Set encrypts and stores a secret, replacing any previous value.
+func (s *Store) Set(name, value string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	sealed, err := s.seal(name, []byte(value))
+	if err != nil {
+		return err
+	}
+	return database.SetSecret(s.db, name, sealed)
+}
+
+// Get decrypts a secret, returning ErrNotFound if there is none.
+func (s *Store) Get(name string) (string, error) {
+	secret, err := database.GetSecret(s.db, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: '%s'", ErrNotFound, name)
+	} else if err != nil {
+		return "", err
+	}
+	value, err := s.open(name, secret.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret '%s': %w", name, err)
+	}
+	return string(value), nil
+}
+
+// Resolve fills in the value of every secret placeholder. Secret values
+// are keyed by the placeholder name, so they can't be set from any other
+// source.
+func (s *Store) Resolve(placeholders []executor.Placeholder, inputs map[string]string) error {
+	for _, ph := range placeholders {
+		if !ph.Secret {
+			continue
+		}
+		value, err := s.Get(ph.SecretName())
+		if err != nil {
+			return err
+		}
+		inputs[ph.Name] = value
+	}
+	return nil
+}
+
+// NeedsSecrets reports whether any of the placeholders is a secret.
+func NeedsSecrets(placeholders []executor.Placeholder) bool {
+	for _, ph := range placeholders {
+		if ph.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+// seal encrypts value, bound to name so a sealed value can't be swapped for
+// another secret's. The nonce is stored in front of the ciphertext.
+func (s *Store) seal(name string, value []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, value, []byte(name)), nil
+}
+
+func (s *Store) open(name string, sealed []byte) ([]byte, error) {
+	size := s.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, errors.New("value too short")
+	}
+	return s.aead.Open(nil, sealed[:size], sealed[size:], []byte(name))
+}
+
+// KeyFromEnv returns key material from $BASHHUB_SECRETS_KEY_FILE, keyFile
+// (from config.json), or $BASHHUB_SECRETS_PASSPHRASE, in that order. It
+// returns nil when none is set, leaving the caller to ask for a passphrase.
+func KeyFromEnv(keyFile string) ([]byte, error) {
+	if path := os.Getenv(EnvKeyFile); path != "" {
+		keyFile = path
+	}
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secrets key file: %w", err)
+		}
+		key := []byte(strings.TrimSpace(string(data)))
+		if len(key) == 0 {
+			return nil, fmt.Errorf("secrets key file %s is empty", keyFile)
+		}
+		return key, nil
+	}
+	if passphrase := os.Getenv(EnvPassphrase); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	return nil, nil
+}
+
+// GenerateKey returns a random key suitable for a key file.
+func GenerateKey() (string, error) {
+	key := make([]byte, keyLength)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// clipboardCommands are tried in order to copy text to the clipboard
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+}
+
+// copyToClipboard copies text with the first clipboard tool found, falling
+// back to asking the terminal to do it (OSC 52), which also works over SSH
+// in terminals that support it.
+func (ui *UI) copyToClipboard(text string) error {
+	for _, args := range clipboardCommands {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err == nil {
+			return nil
+		}
+	}
+
+	if ui.screen == nil {
+		return errors.New("no clipboard available")
+	}
+	ui.screen.SetClipboard([]byte(text))
+	return nil
+}
@@ -2,14 +2,26 @@ package tui
 
 import (
 	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/maccalsa/bashhub/internal/executor"
 	"github.com/rivo/tview"
 )
 
 func highlightCode(code, language string) string {
+	return tview.TranslateANSI(HighlightANSI(code, language))
+}
+
+// HighlightANSI colours code for a 256-colour terminal, returning it
+// unchanged if it can't be highlighted.
+func HighlightANSI(code, language string) string {
 	lexer := lexers.Get(language)
 	if lexer == nil {
 		lexer = lexers.Fallback
@@ -37,16 +49,120 @@ func highlightCode(code, language string) string {
 		return code
 	}
 
-	return tview.TranslateANSI(buff.String())
+	return buff.String()
 }
 
+// highlightSubstitutions highlights code for a TextView like highlightCode,
+// with the substituted placeholder values underlined on a dark yellow
+// background so they stand out.
+func highlightSubstitutions(code, language string, substitutions []executor.Substitution) string {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	style := styles.Get("monokai")
+	if style == nil {
+		style = styles.Fallback
+	}
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return tview.Escape(code)
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, token := range iterator.Tokens() {
+		color := "-"
+		if entry := style.Get(token.Type); entry.Colour.IsSet() {
+			color = entry.Colour.String()
+		}
+
+		// Split the token where a substitution starts or ends
+		text := token.Value
+		for text != "" {
+			inside, boundary := false, pos+len(text)
+			for _, sub := range substitutions {
+				if pos >= sub.Start && pos < sub.End {
+					inside, boundary = true, min(boundary, sub.End)
+				} else if sub.Start > pos {
+					boundary = min(boundary, sub.Start)
+				}
+			}
+			part := text[:boundary-pos]
+			if inside {
+				fmt.Fprintf(&b, "[%s:#5f5f00:bu]%s", color, tview.Escape(part))
+			} else {
+				fmt.Fprintf(&b, "[%s:-:-]%s", color, tview.Escape(part))
+			}
+			text = text[len(part):]
+			pos = boundary
+		}
+	}
+	b.WriteString("[-:-:-]")
+	return b.String()
+}
+
+// DetectLanguage guesses the language of a script from its shebang line,
+// falling back to chroma's content analysis and then to bash.
 func DetectLanguage(scriptContent string) string {
-    lexer := lexers.Analyse(scriptContent)
-    if lexer == nil {
-        lexer = lexers.Match(scriptContent)
-    }
-    if lexer == nil {
-        return "bash" // clearly fallback to bash
-    }
-    return lexer.Config().Name
-}
\ No newline at end of file
+	return DetectLanguageForFile("", scriptContent)
+}
+
+// DetectLanguageForFile is DetectLanguage with a file name whose extension is
+// tried after the shebang.
+func DetectLanguageForFile(fileName, scriptContent string) string {
+	lexer := shebangLexer(scriptContent)
+	if lexer == nil && fileName != "" {
+		lexer = lexers.Match(filepath.Base(fileName))
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(scriptContent)
+	}
+	if lexer == nil {
+		return "bash" // clearly fallback to bash
+	}
+	return lexer.Config().Name
+}
+
+var versionSuffix = regexp.MustCompile(`[\d.]+$`)
+
+// interpreterAliases names the lexer for interpreters chroma doesn't know
+var interpreterAliases = map[string]string{
+	"node":   "javascript",
+	"nodejs": "javascript",
+	"deno":   "javascript",
+	"pwsh":   "powershell",
+}
+
+// shebangLexer finds the lexer for the interpreter named on a "#!" line,
+// e.g. "#!/usr/bin/env python3" gives the Python lexer.
+func shebangLexer(scriptContent string) chroma.Lexer {
+	if !strings.HasPrefix(scriptContent, "#!") {
+		return nil
+	}
+	line, _, _ := strings.Cut(scriptContent[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	name := filepath.Base(fields[0])
+	if name == "env" {
+		name = ""
+		for _, arg := range fields[1:] {
+			if !strings.HasPrefix(arg, "-") {
+				name = filepath.Base(arg)
+				break
+			}
+		}
+	}
+	if name == "" {
+		return nil
+	}
+
+	name = versionSuffix.ReplaceAllString(name, "")
+	if alias, ok := interpreterAliases[name]; ok {
+		name = alias
+	}
+	return lexers.Get(name)
+}
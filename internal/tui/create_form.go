@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
 	"github.com/rivo/tview"
 )
 
@@ -18,6 +19,10 @@ func (ui *UI) showCreateForm() {
 		AddInputField("Name", "", 20, nil, nil).
 		AddInputField("Description", "", 40, nil, nil).
 		AddInputField("Category", "General", 20, nil, nil). // clearly added category
+		AddInputField("Tags", "", 30, nil, nil).
+		AddCheckbox("Raw placeholders", false, nil).
+		AddInputField("Interpreter", "", 30, nil, nil).
+		AddInputField("Allowed checks", "", 30, nil, nil).
 		AddButton("Edit Content", func() {
 			// After editing completes, your TUI restores control, completely avoiding the terminal output leak.
 			content, err := launchEditor(ui.app, scriptContent)
@@ -25,29 +30,44 @@ func (ui *UI) showCreateForm() {
 				ui.details.SetText(fmt.Sprintf("[red]Editor error: %v", err))
 			} else {
 				scriptContent = content
-				form.GetButton(form.GetButtonCount()-3).SetLabel("Edit Content ✔️")
+				form.GetButton(form.GetButtonCount() - 3).SetLabel("Edit Content ✔️")
 			}
 		}).
 		AddButton("Save", func() {
 			name := form.GetFormItemByLabel("Name").(*tview.InputField).GetText()
 			description := form.GetFormItemByLabel("Description").(*tview.InputField).GetText()
 			category := form.GetFormItemByLabel("Category").(*tview.InputField).GetText()
-			
+			tags, err := database.ParseTags(form.GetFormItemByLabel("Tags").(*tview.InputField).GetText())
+			if err != nil {
+				form.SetTitle("New Script - [red]" + tview.Escape(err.Error()))
+				return
+			}
+			rawPlaceholders := form.GetFormItemByLabel("Raw placeholders").(*tview.Checkbox).IsChecked()
+			interpreter := form.GetFormItemByLabel("Interpreter").(*tview.InputField).GetText()
+			allowedChecks, err := executor.ParseAllowlist(form.GetFormItemByLabel("Allowed checks").(*tview.InputField).GetText())
+			if err != nil {
+				form.SetTitle("New Script - [red]" + tview.Escape(err.Error()))
+				return
+			}
+
 			if scriptContent == "" {
 				ui.inForm = false
 				ui.details.SetText("[red]Script content cannot be empty. Please edit script content first.")
 				return
 			}
 
-		    language := DetectLanguage(scriptContent) // automatic detection clearly here
-
+			language := DetectLanguage(scriptContent) // automatic detection clearly here
 
 			script := database.Script{
-				Name: name, 
-				Description: description, 
-				Content: scriptContent,
-				Category: category,
-				Language: language,
+				Name:            name,
+				Description:     description,
+				Content:         scriptContent,
+				Category:        category,
+				Language:        language,
+				RawPlaceholders: rawPlaceholders,
+				Interpreter:     interpreter,
+				AllowedChecks:   allowedChecks,
+				Tags:            tags,
 			}
 
 			if err := database.CreateScript(ui.db, script); err != nil {
@@ -66,4 +86,4 @@ func (ui *UI) showCreateForm() {
 
 	form.SetBorder(true).SetTitle("New Script").SetTitleAlign(tview.AlignLeft)
 	ui.app.SetRoot(form, true)
-}
\ No newline at end of file
+}
@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
+	"github.com/rivo/tview"
+)
+
+// confirmDangerous calls run straight away unless the final script contains
+// dangerous commands the script isn't allowed, in which case it asks first.
+// "Always allow" adds the reported checks to the script's allowlist.
+func (ui *UI) confirmDangerous(script database.Script, finalScript string, mask []string, run func()) {
+	findings := executor.FilterAllowed(executor.Analyze(finalScript), script.AllowedChecks)
+	if len(findings) == 0 {
+		run()
+		return
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "'%s' looks dangerous:\n\n", tview.Escape(script.Name))
+	var checks []string
+	for _, finding := range findings {
+		text.WriteString(tview.Escape(executor.MaskValues(finding.String(), mask)) + "\n")
+		checks = append(checks, finding.Check)
+	}
+
+	ui.inForm = true
+	modal := tview.NewModal().
+		SetText(text.String()).
+		AddButtons([]string{"Cancel", "Run anyway", "Always allow"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			switch buttonLabel {
+			case "Always allow":
+				script.AllowedChecks = executor.AllowChecks(script.AllowedChecks, checks)
+				if err := database.UpdateScript(ui.db, script); err != nil {
+					ui.details.SetText(fmt.Sprintf("[red]Failed to update script: %v", err))
+					break
+				}
+				ui.loadScripts()
+				run()
+				return
+			case "Run anyway":
+				run()
+				return
+			}
+			ui.inForm = false
+			ui.app.SetRoot(ui.root, true)
+		})
+
+	ui.app.SetRoot(modal, false)
+}
@@ -2,12 +2,13 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
 	"github.com/rivo/tview"
 )
 
-
 func (ui *UI) showEditForm() {
 	node := ui.tree.GetCurrentNode()
 	if node == nil {
@@ -30,19 +31,35 @@ func (ui *UI) showEditForm() {
 		AddInputField("Name", script.Name, 20, nil, nil).
 		AddInputField("Description", script.Description, 40, nil, nil).
 		AddInputField("Category", script.Category, 20, nil, nil).
+		AddInputField("Tags", strings.Join(script.Tags, " "), 30, nil, nil).
+		AddCheckbox("Raw placeholders", script.RawPlaceholders, nil).
+		AddInputField("Interpreter", script.Interpreter, 30, nil, nil).
+		AddInputField("Allowed checks", script.AllowedChecks, 30, nil, nil).
 		AddButton("Edit Content", func() {
 			content, err := launchEditor(ui.app, scriptContent)
 			if err != nil {
 				ui.details.SetText(fmt.Sprintf("[red]Editor error: %v", err))
 			} else {
 				scriptContent = content
-				form.GetButton(form.GetButtonCount()-3).SetLabel("Edit Content ✔️")
+				form.GetButton(form.GetButtonCount() - 3).SetLabel("Edit Content ✔️")
 			}
 		}).
 		AddButton("Save", func() {
 			name := form.GetFormItemByLabel("Name").(*tview.InputField).GetText()
 			description := form.GetFormItemByLabel("Description").(*tview.InputField).GetText()
 			category := form.GetFormItemByLabel("Category").(*tview.InputField).GetText()
+			tags, err := database.ParseTags(form.GetFormItemByLabel("Tags").(*tview.InputField).GetText())
+			if err != nil {
+				form.SetTitle("Edit Script - [red]" + tview.Escape(err.Error()))
+				return
+			}
+			rawPlaceholders := form.GetFormItemByLabel("Raw placeholders").(*tview.Checkbox).IsChecked()
+			interpreter := form.GetFormItemByLabel("Interpreter").(*tview.InputField).GetText()
+			allowedChecks, err := executor.ParseAllowlist(form.GetFormItemByLabel("Allowed checks").(*tview.InputField).GetText())
+			if err != nil {
+				form.SetTitle("Edit Script - [red]" + tview.Escape(err.Error()))
+				return
+			}
 
 			if scriptContent == "" {
 				ui.details.SetText("[red]Script content cannot be empty. Please edit script content first.")
@@ -53,6 +70,10 @@ func (ui *UI) showEditForm() {
 			script.Description = description
 			script.Category = category
 			script.Content = scriptContent
+			script.RawPlaceholders = rawPlaceholders
+			script.Interpreter = interpreter
+			script.AllowedChecks = allowedChecks
+			script.Tags = tags
 			script.Language = DetectLanguage(scriptContent)
 
 			if err := database.UpdateScript(ui.db, script); err != nil {
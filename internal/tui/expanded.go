@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/rivo/tview"
+)
+
+// settingCollapsed stores the keys of the tree groups left collapsed, as a
+// JSON list, so the tree opens the way it was left.
+const settingCollapsed = "tui.collapsed"
+
+// loadCollapsed reads the collapsed groups saved by an earlier session.
+func (ui *UI) loadCollapsed() error {
+	ui.collapsed = make(map[string]bool)
+	value, err := database.GetSetting(ui.db, settingCollapsed)
+	if err != nil || value == "" {
+		return err
+	}
+	var keys []string
+	if err := json.Unmarshal([]byte(value), &keys); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		ui.collapsed[key] = true
+	}
+	return nil
+}
+
+// saveCollapsed stores the collapsed groups for the next session.
+func (ui *UI) saveCollapsed() {
+	keys := []string{}
+	for key := range ui.collapsed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	value, _ := json.Marshal(keys)
+	if err := database.SetSetting(ui.db, settingCollapsed, string(value)); err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Failed to save the tree state: %s", tview.Escape(err.Error())))
+	}
+}
+
+// rememberExpanded records whether a group node is expanded.
+func (ui *UI) rememberExpanded(node *tview.TreeNode) {
+	key, ok := ui.groupKeys[node]
+	if !ok {
+		return
+	}
+	if node.IsExpanded() {
+		delete(ui.collapsed, key)
+	} else {
+		ui.collapsed[key] = true
+	}
+	ui.saveCollapsed()
+}
+
+// moveCollapsed carries the collapsed state of a category and its
+// subcategories over to where they were moved.
+func (ui *UI) moveCollapsed(from, to string) {
+	var moved []string
+	for key := range ui.collapsed {
+		if category, ok := strings.CutPrefix(key, groupKey("", false)); ok && database.InCategory(category, from) {
+			moved = append(moved, category)
+		}
+	}
+	if len(moved) == 0 {
+		return
+	}
+	for _, category := range moved {
+		delete(ui.collapsed, groupKey(category, false))
+	}
+	for _, category := range moved {
+		ui.collapsed[groupKey(to+strings.TrimPrefix(category, from), false)] = true
+	}
+	ui.saveCollapsed()
+}
@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/rivo/tview"
+)
+
+// Synthetic groups shown above the categories
+const (
+	favoritesGroup = "★ Favorites"
+	recentGroup    = "Recent"
+	// recentLimit is how many recently run scripts are listed
+	recentLimit = 10
+)
+
+// addPinnedGroups adds the favorites, by name, and the most recently run
+// scripts, newest first, at the top of root.
+func (ui *UI) addPinnedGroups(root *tview.TreeNode, scripts []database.Script) {
+	var err error
+	if ui.favorites, err = database.GetFavorites(ui.db); err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Error loading favorites: %v", err))
+		return
+	}
+	if ui.usage, err = database.GetUsage(ui.db); err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Error loading run history: %v", err))
+		return
+	}
+
+	var favorites, recent []database.Script
+	for _, script := range scripts {
+		if ui.favorites[script.ID] {
+			favorites = append(favorites, script)
+		}
+		if _, ok := ui.usage[script.ID]; ok {
+			recent = append(recent, script)
+		}
+	}
+	sort.Slice(favorites, func(i, j int) bool {
+		return strings.ToLower(favorites[i].Name) < strings.ToLower(favorites[j].Name)
+	})
+	sort.Slice(recent, func(i, j int) bool {
+		return ui.usage[recent[i].ID].LastRun.After(ui.usage[recent[j].ID].LastRun)
+	})
+	if len(recent) > recentLimit {
+		recent = recent[:recentLimit]
+	}
+
+	ui.pinned = nil
+	for _, group := range []struct {
+		label, key string
+		scripts    []database.Script
+	}{
+		{favoritesGroup, "pinned/favorites", favorites},
+		{recentGroup, "pinned/recent", recent},
+	} {
+		if len(group.scripts) == 0 {
+			continue
+		}
+		node := ui.newGroupNode(group.label, group.key, true).SetColor(tcell.ColorGold)
+		for _, script := range group.scripts {
+			node.AddChild(newScriptNode(script))
+		}
+		ui.pinned = append(ui.pinned, node)
+	}
+	root.SetChildren(append(append([]*tview.TreeNode(nil), ui.pinned...), root.GetChildren()...))
+}
+
+// refreshPinned rebuilds the favorites and recent groups of the script tree
+// in place, keeping the selection unless it was inside them. Search results
+// have no such groups and are left alone.
+func (ui *UI) refreshPinned() {
+	if ui.searchBox.GetText() != "" {
+		return
+	}
+	scripts, err := database.GetScripts(ui.db)
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Error loading scripts: %v", err))
+		return
+	}
+
+	root := ui.tree.GetRoot()
+	current := ui.tree.GetCurrentNode()
+	for _, node := range ui.pinned {
+		if node == current || contains(node.GetChildren(), current) {
+			ui.tree.SetCurrentNode(root)
+		}
+		root.RemoveChild(node)
+		delete(ui.groupKeys, node)
+	}
+	ui.addPinnedGroups(root, scripts)
+}
+
+// toggleFavorite pins the selected script to the favorites, or unpins it.
+func (ui *UI) toggleFavorite() {
+	node := ui.tree.GetCurrentNode()
+	if node == nil || node.GetReference() == nil {
+		ui.details.SetText("[red]Please select a script to pin or unpin.")
+		return
+	}
+	script := node.GetReference().(database.Script)
+
+	favorite := !ui.favorites[script.ID]
+	if err := database.SetFavorite(ui.db, script.ID, favorite); err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Failed to update favorites: %v", err))
+		return
+	}
+	ui.refreshPinned()
+	if favorite {
+		ui.details.SetText(fmt.Sprintf("[green]Added '%s' to favorites.", tview.Escape(script.Name)))
+	} else {
+		ui.details.SetText(fmt.Sprintf("[green]Removed '%s' from favorites.", tview.Escape(script.Name)))
+	}
+}
+
+func contains(nodes []*tview.TreeNode, node *tview.TreeNode) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
@@ -1,86 +1,269 @@
 package tui
 
 import (
-	"strings"
-
 	"fmt"
+	"math"
 	"sort"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/fuzzy"
 	"github.com/rivo/tview"
 )
 
+// snippetHighlighter turns search match markers into tview colour tags.
+var snippetHighlighter = strings.NewReplacer(
+	database.MatchStart, "[black:yellow]",
+	database.MatchEnd, "[-:-]",
+)
+
+// tagPrefix marks a search word as a tag filter, e.g. "tag:k8s deploy"
+const tagPrefix = "tag:"
+
+// splitTagFilters separates tag:name words from the rest of a query.
+func splitTagFilters(query string) (string, []string) {
+	var words, tags []string
+	for _, word := range strings.Fields(query) {
+		if tag, ok := strings.CutPrefix(strings.ToLower(word), tagPrefix); ok {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), tags
+}
+
+// Search fields matched fuzzily, and how much a match in each counts
+const (
+	fieldName = iota
+	fieldCategory
+	fieldTags
+	fieldDescription
+	fieldCount
+)
+
+var fieldWeights = [fieldCount]int{2, 1, 1, 1}
+
+// usageWeight scales the bonus for scripts that are run often: each
+// doubling of the run count adds this much to the score.
+const usageWeight = 6
+
+// matchStyle highlights matched characters in search results
+const matchStyle = "[black:yellow]"
+
+// searchHit is a script found by the search box
+type searchHit struct {
+	script database.Script
+	score  int
+	// positions are the matched runes of each field
+	positions [fieldCount][]int
+	// snippet is set for scripts only found in their content
+	snippet string
+}
+
 func (ui *UI) filterScripts(query string) {
 	rootNode := tview.NewTreeNode(fmt.Sprintf("Search: '%s'", query)).SetColor(tcell.ColorYellow)
 	ui.tree.SetRoot(rootNode).SetCurrentNode(rootNode)
 
-	scripts, err := database.GetScripts(ui.db)
+	all, err := database.GetScripts(ui.db)
 	if err != nil {
 		ui.details.SetText(fmt.Sprintf("[red]Error loading scripts: %v", err))
 		return
 	}
 
-	query = strings.ToLower(query)
+	// tag: words narrow the scripts searched, all of them must match
+	query, tags := splitTagFilters(query)
+	var scripts []database.Script
+	for _, script := range all {
+		if script.HasTags(tags) {
+			scripts = append(scripts, script)
+		}
+	}
 
-	// First group scripts clearly by category
-	catMap := make(map[string][]database.Script)
-	for _, script := range scripts {
-		catMap[script.Category] = append(catMap[script.Category], script)
+	if query == "" {
+		// Only tags: show what carries them, grouped as usual
+		groups, names := groupScripts(scripts, ui.groupByTag)
+		for _, group := range names {
+			sort.Slice(groups[group], func(i, j int) bool {
+				return strings.ToLower(groups[group][i].Name) < strings.ToLower(groups[group][j].Name)
+			})
+		}
+		ui.addGroups(rootNode, groups, names, false)
+		ui.tree.SetSelectedFunc(func(node *tview.TreeNode) {
+			if ref := node.GetReference(); ref != nil {
+				ui.showSearchHit(searchHit{script: ref.(database.Script)})
+			} else {
+				node.SetExpanded(!node.IsExpanded())
+			}
+		})
+		return
 	}
 
-	// Extract and sort categories explicitly (case insensitive)
-	var categories []string
-	for category := range catMap {
-		categories = append(categories, category)
+	hits, err := ui.rankScripts(scripts, query)
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Search failed: %v", err))
+		return
+	}
+
+	// A flat list, best first
+	for _, hit := range hits {
+		label := highlightMatches(hit.script.Name, hit.positions[fieldName])
+		label += "  [gray]" + highlightMatches(hit.script.Category, hit.positions[fieldCategory])
+		if len(hit.script.Tags) > 0 {
+			label += "  [gray]" + highlightMatches(strings.Join(hit.script.Tags, " "), hit.positions[fieldTags])
+		}
+		rootNode.AddChild(tview.NewTreeNode(label).
+			SetReference(hit.script).
+			SetColor(tcell.ColorWhite))
 	}
-	sort.Slice(categories, func(i, j int) bool {
-		return strings.ToLower(categories[i]) < strings.ToLower(categories[j])
+
+	byID := make(map[int64]searchHit, len(hits))
+	for _, hit := range hits {
+		byID[hit.script.ID] = hit
+	}
+	ui.tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		if ref := node.GetReference(); ref != nil {
+			ui.showSearchHit(byID[ref.(database.Script).ID])
+		}
 	})
 
-	for _, category := range categories {
-		categoryLower := strings.ToLower(category)
-		var matchingScripts []database.Script
+	// Select the top hit, so Enter in the search box lands on it
+	if len(hits) > 0 {
+		ui.tree.SetCurrentNode(rootNode.GetChildren()[0])
+		ui.showSearchHit(hits[0])
+	} else {
+		ui.details.SetText("[yellow]No matching scripts.")
+	}
+}
 
-		// Check if category matches query
-		if strings.Contains(categoryLower, query) {
-			// clearly include all scripts in matching category
-			matchingScripts = catMap[category]
-		} else {
-			// explicitly filter scripts matching query clearly
-			for _, script := range catMap[category] {
-				if strings.Contains(strings.ToLower(script.Name), query) ||
-					strings.Contains(strings.ToLower(script.Description), query) {
-					matchingScripts = append(matchingScripts, script)
-				}
+// rankScripts matches every word of query fuzzily against the name,
+// category, tags and description of scripts, adding a bonus for scripts run
+// often. Scripts only matching in their content, found by full-text search,
+// follow the fuzzy matches.
+func (ui *UI) rankScripts(scripts []database.Script, query string) ([]searchHit, error) {
+	usage, err := database.GetUsage(ui.db)
+	if err != nil {
+		return nil, err
+	}
+	results, err := database.SearchScripts(ui.db, query, 0)
+	if err != nil {
+		return nil, err
+	}
+	contentRank := make(map[int64]int)
+	snippets := make(map[int64]string)
+	for i, result := range results {
+		contentRank[result.ID] = i
+		snippets[result.ID] = result.Snippet
+	}
+
+	var hits, contentHits []searchHit
+	for _, script := range scripts {
+		hit, ok := matchScript(script, strings.Fields(query))
+		hit.snippet = snippets[script.ID]
+		if ok {
+			if u, ok := usage[script.ID]; ok {
+				hit.score += int(usageWeight * math.Log2(float64(u.Runs)+1))
 			}
+			hits = append(hits, hit)
+		} else if _, ok := contentRank[script.ID]; ok {
+			contentHits = append(contentHits, hit)
 		}
+	}
 
-		if len(matchingScripts) > 0 {
-			// explicitly sort scripts clearly within category
-			sort.Slice(matchingScripts, func(i, j int) bool {
-				return strings.ToLower(matchingScripts[i].Name) < strings.ToLower(matchingScripts[j].Name)
-			})
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.script.Name) != len(b.script.Name) {
+			return len(a.script.Name) < len(b.script.Name)
+		}
+		return strings.ToLower(a.script.Name) < strings.ToLower(b.script.Name)
+	})
+	sort.SliceStable(contentHits, func(i, j int) bool {
+		return contentRank[contentHits[i].script.ID] < contentRank[contentHits[j].script.ID]
+	})
+	return append(hits, contentHits...), nil
+}
 
-			catNode := tview.NewTreeNode(category).SetColor(tcell.ColorGreen)
-			for _, script := range matchingScripts {
-				script := script // capture clearly
-				scriptNode := tview.NewTreeNode(script.Name).
-					SetReference(script).
-					SetColor(tcell.ColorWhite)
-				catNode.AddChild(scriptNode)
+// matchScript scores each word in the field it matches best. It fails if
+// any word matches no field.
+func matchScript(script database.Script, words []string) (searchHit, bool) {
+	hit := searchHit{script: script}
+	fields := [fieldCount]string{
+		fieldName:        script.Name,
+		fieldCategory:    script.Category,
+		fieldTags:        strings.Join(script.Tags, " "),
+		fieldDescription: script.Description,
+	}
+
+	for _, word := range words {
+		// Scores go below zero for matches spread over long gaps
+		found, best, bestField := false, 0, 0
+		var bestPositions []int
+		for field, value := range fields {
+			score, positions, ok := fuzzy.Match(word, value)
+			if ok && (!found || score*fieldWeights[field] > best) {
+				found, best, bestField, bestPositions = true, score*fieldWeights[field], field, positions
 			}
-			rootNode.AddChild(catNode)
 		}
+		if !found {
+			return hit, false
+		}
+		hit.score += best
+		hit.positions[bestField] = append(hit.positions[bestField], bestPositions...)
+	}
+	return hit, true
+}
+
+// highlightMatches escapes text for tview, highlighting the runes at
+// positions.
+func highlightMatches(text string, positions []int) string {
+	matched := make(map[int]bool, len(positions))
+	for _, i := range positions {
+		matched[i] = true
 	}
 
-	ui.tree.SetSelectedFunc(func(node *tview.TreeNode) {
-		ref := node.GetReference()
-		if ref != nil {
-			script := ref.(database.Script)
-			ui.details.SetText(highlightCode(script.Content, script.Language))
+	// Escape runs of text between tags, a rune at a time could leave
+	// brackets that read as a tag once joined
+	var b strings.Builder
+	var run []rune
+	inMatch := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if inMatch {
+			b.WriteString(matchStyle + tview.Escape(string(run)) + "[-:-]")
 		} else {
-			node.SetExpanded(!node.IsExpanded())
+			b.WriteString(tview.Escape(string(run)))
 		}
-	})
+		run = run[:0]
+	}
+	for i, r := range []rune(text) {
+		if matched[i] != inMatch {
+			flush()
+			inMatch = matched[i]
+		}
+		run = append(run, r)
+	}
+	flush()
+	return b.String()
+}
+
+// showSearchHit shows a search result in the details pane, with what
+// matched highlighted.
+func (ui *UI) showSearchHit(hit searchHit) {
+	script := hit.script
+	details := fmt.Sprintf("[yellow]Description:[white] %s\n\n", highlightMatches(script.Description, hit.positions[fieldDescription]))
+	if len(script.Tags) > 0 {
+		details += fmt.Sprintf("[yellow]Tags:[white] %s\n\n", highlightMatches(strings.Join(script.Tags, " "), hit.positions[fieldTags]))
+	}
+	if hit.snippet != "" {
+		details += fmt.Sprintf("[yellow]Match:[white] %s\n\n", snippetHighlighter.Replace(tview.Escape(hit.snippet)))
+	}
+	ui.details.SetText(details + highlightCode(script.Content, script.Language))
 }
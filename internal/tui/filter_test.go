@@ -0,0 +1,26 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maccalsa/bashhub/internal/database"
+)
+
+func TestMatchScriptLongGaps(t *testing.T) {
+	script := database.Script{
+		Name:        "cleanup",
+		Category:    "ops",
+		Description: "Dumps " + strings.Repeat("x", 60) + " to b",
+	}
+	hit, ok := matchScript(script, []string{"db"})
+	if !ok {
+		t.Fatal("expected a match in the description")
+	}
+	if len(hit.positions[fieldDescription]) != 2 {
+		t.Errorf("positions %v", hit.positions)
+	}
+	if _, ok := matchScript(script, []string{"zq"}); ok {
+		t.Error("expected no match")
+	}
+}
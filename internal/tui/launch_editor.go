@@ -3,45 +3,58 @@ package tui
 import (
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/rivo/tview"
 )
 
-
-
 func launchEditor(app *tview.Application, initialContent string) (string, error) {
+	content := initialContent
+	var err error
+
 	// Suspend the tview Application (restore terminal state)
 	app.Suspend(func() {
-		tmpfile, err := os.CreateTemp("", "bashhub-*.sh")
-		if err != nil {
-			return
-		}
-		defer os.Remove(tmpfile.Name())
-
-		tmpfile.Write([]byte(initialContent))
-		tmpfile.Close()
-
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			editor = "nano" // default fallback
-		}
-
-		cmd := exec.Command(editor, tmpfile.Name())
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Run(); err != nil {
-			return
-		}
-
-		updatedContent, err := os.ReadFile(tmpfile.Name())
-		if err != nil {
-			return
-		}
-
-		initialContent = string(updatedContent)
+		content, err = EditContent(initialContent)
 	})
 
-	return initialContent, nil
-}
\ No newline at end of file
+	if err != nil {
+		return initialContent, err
+	}
+	return content, nil
+}
+
+// EditContent opens content in $EDITOR (nano if unset) on the current
+// terminal and returns what was saved.
+func EditContent(initialContent string) (string, error) {
+	tmpfile, err := os.CreateTemp("", "bashhub-*.sh")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.Write([]byte(initialContent))
+	tmpfile.Close()
+	if err != nil {
+		return "", err
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"nano"} // default fallback
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], tmpfile.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	updatedContent, err := os.ReadFile(tmpfile.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(updatedContent), nil
+}
@@ -12,7 +12,11 @@ import (
 )
 
 func (ui *UI) loadScripts() {
-	rootNode := tview.NewTreeNode("Scripts").SetColor(tcell.ColorYellow)
+	title := "Scripts"
+	if ui.groupByTag {
+		title = "Scripts by tag"
+	}
+	rootNode := tview.NewTreeNode(title).SetColor(tcell.ColorYellow)
 	ui.tree.SetRoot(rootNode).SetCurrentNode(rootNode)
 
 	scripts, err := database.GetScripts(ui.db)
@@ -21,40 +25,15 @@ func (ui *UI) loadScripts() {
 		return
 	}
 
-	catMap := make(map[string][]database.Script)
-	for _, script := range scripts {
-		catMap[script.Category] = append(catMap[script.Category], script)
-	}
-
-	// Extract categories and sort explicitly (case-insensitive)
-	var categories []string
-	for category := range catMap {
-		categories = append(categories, category)
-	}
-	sort.Slice(categories, func(i, j int) bool {
-		return strings.ToLower(categories[i]) < strings.ToLower(categories[j])
-	})
-
-	for _, category := range categories {
-		// explicitly sort scripts within category
-		sort.Slice(catMap[category], func(i, j int) bool {
-			return strings.ToLower(catMap[category][i].Name) < strings.ToLower(catMap[category][j].Name)
+	groups, names := groupScripts(scripts, ui.groupByTag)
+	for _, group := range names {
+		// explicitly sort scripts within group
+		sort.Slice(groups[group], func(i, j int) bool {
+			return strings.ToLower(groups[group][i].Name) < strings.ToLower(groups[group][j].Name)
 		})
-
-		catNode := tview.NewTreeNode(category).
-			SetColor(tcell.ColorGreen)
-
-		for _, script := range catMap[category] {
-			script := script // capture clearly
-			scriptNode := tview.NewTreeNode(script.Name).
-				SetReference(script).
-				SetColor(tcell.ColorWhite).
-				SetSelectable(true)
-
-			catNode.AddChild(scriptNode)
-		}
-		rootNode.AddChild(catNode)
 	}
+	ui.addGroups(rootNode, groups, names, true)
+	ui.addPinnedGroups(rootNode, scripts)
 
 	ui.tree.SetSelectedFunc(func(node *tview.TreeNode) {
 		ref := node.GetReference()
@@ -65,10 +44,129 @@ func (ui *UI) loadScripts() {
 				SetRegions(true).
 				SetWrap(true)
 
-			details := fmt.Sprintf("[yellow]Description:[white] %s\n\n%s", script.Description, highlightCode(script.Content, script.Language))
-			ui.details.SetText(details)
+			details := fmt.Sprintf("[yellow]Description:[white] %s\n\n", script.Description)
+			if len(script.Tags) > 0 {
+				details += fmt.Sprintf("[yellow]Tags:[white] %s\n\n", strings.Join(script.Tags, ", "))
+			}
+			if usage, ok := ui.usage[script.ID]; ok {
+				details += fmt.Sprintf("[yellow]Runs:[white] %d, last %s\n\n", usage.Runs, usage.LastRun.Local().Format("2006-01-02 15:04"))
+			}
+			ui.details.SetText(details + highlightCode(script.Content, script.Language))
 		} else {
 			node.SetExpanded(!node.IsExpanded())
+			ui.rememberExpanded(node)
+		}
+	})
+}
+
+// addGroups adds a node for each group to root, holding its scripts in the
+// order given. Categories nest by path, so "AWS/EC2" becomes EC2 inside
+// AWS. With remember, groups collapsed in an earlier session start
+// collapsed; otherwise everything is expanded.
+func (ui *UI) addGroups(root *tview.TreeNode, groups map[string][]database.Script, names []string, remember bool) {
+	ui.groupKeys = make(map[*tview.TreeNode]string)
+	nodes := make(map[string]*tview.TreeNode)
+
+	var groupNode func(levels []string) *tview.TreeNode
+	groupNode = func(levels []string) *tview.TreeNode {
+		key := groupKey(strings.Join(levels, database.CategorySeparator), ui.groupByTag)
+		if node, ok := nodes[key]; ok {
+			return node
+		}
+		parent := root
+		if len(levels) > 1 {
+			parent = groupNode(levels[:len(levels)-1])
+		}
+		node := ui.newGroupNode(levels[len(levels)-1], key, remember).SetColor(tcell.ColorGreen)
+		parent.AddChild(node)
+		nodes[key] = node
+		return node
+	}
+
+	levelsOf := func(name string) []string {
+		if levels := database.SplitCategory(name); !ui.groupByTag && len(levels) > 0 {
+			return levels
+		}
+		return []string{name}
+	}
+
+	// Sort level by level, so a category's subcategories follow it
+	names = append([]string(nil), names...)
+	if !ui.groupByTag {
+		sort.SliceStable(names, func(i, j int) bool {
+			a, b := levelsOf(names[i]), levelsOf(names[j])
+			for k := 0; k < len(a) && k < len(b); k++ {
+				if x, y := strings.ToLower(a[k]), strings.ToLower(b[k]); x != y {
+					return x < y
+				}
+			}
+			return len(a) < len(b)
+		})
+	}
+
+	for _, name := range names {
+		node := groupNode(levelsOf(name))
+		for _, script := range groups[name] {
+			node.AddChild(newScriptNode(script))
+		}
+	}
+}
+
+// newGroupNode creates the node of a group identified by key. With
+// remember, it starts collapsed if it was left collapsed.
+func (ui *UI) newGroupNode(label, key string, remember bool) *tview.TreeNode {
+	node := tview.NewTreeNode(label).SetExpanded(!remember || !ui.collapsed[key])
+	ui.groupKeys[node] = key
+	return node
+}
+
+func newScriptNode(script database.Script) *tview.TreeNode {
+	return tview.NewTreeNode(script.Name).
+		SetReference(script).
+		SetColor(tcell.ColorWhite).
+		SetSelectable(true)
+}
+
+// groupKey identifies a group node across sessions
+func groupKey(name string, byTag bool) string {
+	if byTag {
+		return "tag/" + name
+	}
+	return "category/" + name
+}
+
+// untaggedGroup holds scripts without tags when grouping by tag
+const untaggedGroup = "(untagged)"
+
+// groupScripts groups scripts by category, or by tag with a script listed
+// under each of its tags. Group names are sorted case-insensitively, with
+// untagged scripts last.
+func groupScripts(scripts []database.Script, byTag bool) (map[string][]database.Script, []string) {
+	groups := make(map[string][]database.Script)
+	for _, script := range scripts {
+		switch {
+		case !byTag:
+			groups[script.Category] = append(groups[script.Category], script)
+		case len(script.Tags) == 0:
+			groups[untaggedGroup] = append(groups[untaggedGroup], script)
+		default:
+			for _, tag := range script.Tags {
+				groups[tag] = append(groups[tag], script)
+			}
 		}
+	}
+
+	var names []string
+	for name := range groups {
+		if name != untaggedGroup || !byTag {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
 	})
+	if _, ok := groups[untaggedGroup]; ok && byTag {
+		names = append(names, untaggedGroup)
+	}
+	return groups, names
 }
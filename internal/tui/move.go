@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/rivo/tview"
+)
+
+// showMoveForm moves the selected script to another category, or the
+// selected category with its subcategories under another path.
+func (ui *UI) showMoveForm() {
+	node := ui.tree.GetCurrentNode()
+	if node == nil {
+		return
+	}
+
+	var script *database.Script
+	var from string
+	if ref := node.GetReference(); ref != nil {
+		s := ref.(database.Script)
+		script = &s
+		from = s.Category
+	} else {
+		key, ok := ui.groupKeys[node]
+		category, isCategory := strings.CutPrefix(key, groupKey("", false))
+		if !ok || !isCategory {
+			ui.details.SetText("[red]Select a script or a category to move. Press T to group by category.")
+			return
+		}
+		from = category
+	}
+
+	title := "Move category " + from
+	if script != nil {
+		title = "Move " + script.Name
+	}
+	title = tview.Escape(title)
+
+	ui.inForm = true
+	form := tview.NewForm()
+	form.AddInputField("Category", from, 40, nil, nil)
+
+	form.AddButton("Move", func() {
+		to := database.CleanCategory(form.GetFormItemByLabel("Category").(*tview.InputField).GetText())
+		if to == "" {
+			form.SetTitle(title + " - [red]Category cannot be empty")
+			return
+		}
+
+		var message string
+		if script != nil {
+			script.Category = to
+			if err := database.UpdateScript(ui.db, *script); err != nil {
+				form.SetTitle(title + " - [red]" + tview.Escape(err.Error()))
+				return
+			}
+			message = fmt.Sprintf("Moved '%s' to %s", script.Name, to)
+		} else {
+			moved, err := database.MoveCategory(ui.db, from, to)
+			if err != nil {
+				form.SetTitle(title + " - [red]" + tview.Escape(err.Error()))
+				return
+			}
+			ui.moveCollapsed(from, to)
+			message = fmt.Sprintf("Moved %d scripts from %s to %s", moved, from, to)
+		}
+
+		ui.inForm = false
+		ui.loadScripts()
+		ui.app.SetRoot(ui.root, true)
+		ui.details.SetText("[green]" + tview.Escape(message))
+	}).AddButton("Cancel", func() {
+		ui.inForm = false
+		ui.app.SetRoot(ui.root, true)
+	})
+
+	form.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignLeft)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			ui.inForm = false
+			ui.app.SetRoot(ui.root, true)
+		}
+		return event
+	})
+	ui.app.SetRoot(form, true).SetFocus(form)
+}
@@ -1,29 +1,111 @@
 package tui
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/maccalsa/bashhub/internal/database"
 	"github.com/maccalsa/bashhub/internal/executor"
 	"github.com/rivo/tview"
 )
 
-
-func (ui *UI) promptPlaceholderInputs(script database.Script, placeholders []string) {
+func (ui *UI) promptPlaceholderInputs(script database.Script, placeholders []executor.Placeholder) {
 	inputs := make(map[string]string)
 	form := tview.NewForm()
 	ui.inForm = true
 
+	var fields int
 	for _, ph := range placeholders {
-		form.AddInputField(ph, "", 30, nil, func(text string) {
-			inputs[ph] = text
-		})
+		ph := ph
+		if ph.Secret {
+			continue
+		}
+		fields++
+		label := ph.Name
+		if hint := ph.Describe(); hint != "" {
+			label = tview.Escape(fmt.Sprintf("%s (%s)", ph.Name, hint))
+		}
+		inputs[ph.Name] = ph.Default
+
+		switch ph.Type {
+		case executor.TypeChoice:
+			// Choices become a dropdown, starting on the default if there is one
+			initial := 0
+			for i, choice := range ph.Choices {
+				if choice == ph.Default {
+					initial = i
+				}
+			}
+			inputs[ph.Name] = ph.Choices[initial]
+			form.AddDropDown(label, ph.Choices, initial, func(option string, _ int) {
+				inputs[ph.Name] = option
+			})
+		case executor.TypeBool:
+			checked, _ := strconv.ParseBool(ph.Default)
+			inputs[ph.Name] = strconv.FormatBool(checked)
+			form.AddCheckbox(label, checked, func(checked bool) {
+				inputs[ph.Name] = strconv.FormatBool(checked)
+			})
+		default:
+			var accept func(string, rune) bool
+			switch ph.Type {
+			case executor.TypeInt:
+				accept = tview.InputFieldInteger
+			case executor.TypeFloat:
+				accept = tview.InputFieldFloat
+			}
+			if ph.Sensitive {
+				form.AddPasswordField(label, ph.Default, 30, '*', func(text string) {
+					inputs[ph.Name] = text
+				})
+				continue
+			}
+			form.AddInputField(label, ph.Default, 30, accept, func(text string) {
+				inputs[ph.Name] = text
+			})
+		}
 	}
 
-	form.AddButton("Run", func() {
-		finalScript := executor.ReplacePlaceholders(script.Content, inputs)
-		ui.inForm = false
-		ui.runAndDisplay(finalScript)
-	}).AddButton("Cancel", func() {
+	preview := func() {
+		for _, ph := range placeholders {
+			if ph.Secret {
+				continue
+			}
+			value, err := ph.Resolve(inputs[ph.Name])
+			if err != nil {
+				form.SetTitle("Fill placeholders - [red]" + tview.Escape(err.Error()))
+				return
+			}
+			inputs[ph.Name] = value
+		}
+		if ui.secrets != nil {
+			if err := ui.secrets.Resolve(placeholders, inputs); err != nil {
+				ui.inForm = false
+				ui.app.SetRoot(ui.root, true)
+				ui.details.SetText("[red]" + tview.Escape(err.Error()))
+				return
+			}
+		}
+		var err error
+		if fields == 0 {
+			err = ui.showPreview(script, placeholders, inputs, nil)
+		} else {
+			err = ui.showPreview(script, placeholders, inputs, form)
+		}
+		if err != nil {
+			ui.inForm = false
+			ui.app.SetRoot(ui.root, true)
+			ui.details.SetText("[red]" + tview.Escape(err.Error()))
+		}
+	}
+	if fields == 0 {
+		// Only secrets, nothing to ask for
+		preview()
+		return
+	}
+
+	form.AddButton("Preview", preview).AddButton("Cancel", func() {
 		ui.inForm = false
 		ui.app.SetRoot(ui.root, true)
 	})
@@ -37,4 +119,4 @@ func (ui *UI) promptPlaceholderInputs(script database.Script, placeholders []str
 		return event
 	})
 	ui.app.SetRoot(form, true).SetFocus(form)
-}
\ No newline at end of file
+}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/executor"
+	"github.com/rivo/tview"
+)
+
+// showPreview shows the script with the placeholder values substituted,
+// highlighted and with the values emphasized, before it runs. Sensitive
+// values are masked. Back returns to the form, or the main view when back
+// is nil; Copy copies the script as export would, leaving secrets out.
+func (ui *UI) showPreview(script database.Script, placeholders []executor.Placeholder, inputs map[string]string, back tview.Primitive) error {
+	interpreter := executor.ResolveInterpreter(script.Content, script.Language, script.Interpreter, ui.cfg.Interpreters)
+	quote := !script.RawPlaceholders && executor.IsShell(interpreter)
+	finalScript, err := executor.ReplacePlaceholders(script.Content, inputs, quote)
+	if err != nil {
+		return err
+	}
+
+	shownScript, substitutions, err := executor.SubstitutePlaceholders(script.Content, executor.MaskInputs(placeholders, inputs), quote)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf(" Preview: %s | %s ", script.Name, strings.Join(interpreter, " "))
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(false).
+		SetText(highlightSubstitutions(shownScript, script.Language, substitutions))
+	view.SetBorder(true).SetTitle(tview.Escape(title))
+
+	goBack := func() {
+		if back != nil {
+			ui.inForm = true
+			ui.app.SetRoot(back, true).SetFocus(back)
+			return
+		}
+		ui.inForm = false
+		ui.app.SetRoot(ui.root, true)
+	}
+
+	buttons := tview.NewForm().SetButtonsAlign(tview.AlignCenter)
+	buttons.
+		AddButton("Run", func() {
+			ui.inForm = false
+			ui.confirmDangerous(script, finalScript, executor.SensitiveValues(placeholders, inputs), func() {
+				ui.runAndDisplay(script, finalScript, placeholders, inputs)
+			})
+		}).
+		AddButton("Back", goBack).
+		AddButton("Copy", func() {
+			copied, err := executor.ReplacePlaceholders(script.Content, executor.WithoutSecrets(inputs), quote)
+			if err == nil {
+				err = ui.copyToClipboard(copied)
+			}
+			if err != nil {
+				view.SetTitle(tview.Escape(title) + "- [red]" + tview.Escape(err.Error()) + " ")
+			} else {
+				view.SetTitle(tview.Escape(title) + "- [green]Copied ")
+			}
+		})
+
+	// The buttons keep the focus; arrows scroll the script
+	buttons.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			goBack()
+			return nil
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn, tcell.KeyHome, tcell.KeyEnd:
+			view.InputHandler()(event, nil)
+			return nil
+		}
+		return event
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(view, 0, 1, false).
+		AddItem(buttons, 3, 0, true)
+
+	ui.inForm = true
+	ui.app.SetRoot(layout, true).SetFocus(buttons)
+	return nil
+}
@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/maccalsa/bashhub/internal/secrets"
+	"github.com/rivo/tview"
+)
+
+// unlockSecrets calls then once the secrets are unlocked, with the
+// configured key or a passphrase asked for in a form. The store is kept for
+// the rest of the session.
+func (ui *UI) unlockSecrets(then func()) {
+	if ui.secrets != nil {
+		then()
+		return
+	}
+
+	key, err := secrets.KeyFromEnv(ui.cfg.SecretsKeyFile)
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]%s", tview.Escape(err.Error())))
+		return
+	}
+	if key != nil {
+		if ui.secrets, err = secrets.Unlock(ui.db, key); err != nil {
+			ui.details.SetText(fmt.Sprintf("[red]Failed to unlock secrets: %s", tview.Escape(err.Error())))
+			return
+		}
+		then()
+		return
+	}
+
+	initialized, err := secrets.Initialized(ui.db)
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Failed to read secrets settings: %v", err))
+		return
+	}
+
+	title := "Unlock secrets"
+	if !initialized {
+		title = "Choose a secrets passphrase"
+	}
+
+	ui.inForm = true
+	var passphrase, confirm string
+	form := tview.NewForm()
+	form.AddPasswordField("Passphrase", "", 40, '*', func(text string) {
+		passphrase = text
+	})
+	if !initialized {
+		form.AddPasswordField("Repeat passphrase", "", 40, '*', func(text string) {
+			confirm = text
+		})
+	}
+
+	form.AddButton("Unlock", func() {
+		if !initialized && passphrase != confirm {
+			form.SetTitle(title + " - [red]Passphrases don't match")
+			return
+		}
+		store, err := secrets.Unlock(ui.db, []byte(passphrase))
+		if err != nil {
+			form.SetTitle(title + " - [red]" + tview.Escape(err.Error()))
+			return
+		}
+		ui.secrets = store
+		ui.inForm = false
+		ui.app.SetRoot(ui.root, true)
+		then()
+	}).AddButton("Cancel", func() {
+		ui.inForm = false
+		ui.app.SetRoot(ui.root, true)
+	})
+
+	form.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignLeft)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			ui.inForm = false
+			ui.app.SetRoot(ui.root, true)
+		}
+		return event
+	})
+	ui.app.SetRoot(form, true).SetFocus(form)
+}
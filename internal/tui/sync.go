@@ -0,0 +1,139 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/maccalsa/bashhub/internal/diff"
+	"github.com/maccalsa/bashhub/internal/gitsync"
+	"github.com/rivo/tview"
+)
+
+// openSync returns the sync repository, or nil if sync isn't set up.
+func (ui *UI) openSync() (*gitsync.Repo, error) {
+	repo, err := gitsync.Open(ui.db, DetectLanguageForFile)
+	if errors.Is(err, gitsync.ErrNotConfigured) {
+		return nil, nil
+	}
+	return repo, err
+}
+
+// syncNotice tells about conflicts left by a pull, or returns "".
+func (ui *UI) syncNotice() string {
+	repo, err := ui.openSync()
+	if err != nil || repo == nil {
+		return ""
+	}
+	conflicts, err := repo.Conflicts()
+	if err != nil || len(conflicts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[yellow]%d scripts changed both here and on the sync remote. Press S to resolve.", len(conflicts))
+}
+
+// showSyncConflicts lists the conflicts of an unfinished sync pull with a
+// diff between the two versions. M keeps the library's version, T takes the
+// remote's.
+func (ui *UI) showSyncConflicts() {
+	repo, err := ui.openSync()
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Failed to open sync repository: %v", err))
+		return
+	}
+	if repo == nil {
+		ui.details.SetText("[yellow]Sync is not set up. Run 'bashhub sync init <path>' first.")
+		return
+	}
+
+	conflicts, err := repo.Conflicts()
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Failed to read conflicts: %v", err))
+		return
+	}
+	if len(conflicts) == 0 {
+		ui.details.SetText("[green]No sync conflicts.")
+		return
+	}
+
+	ui.inForm = true
+	preview := tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	preview.SetBorder(true).SetTitle(" Mine → Theirs | M: Keep mine | T: Take theirs | Esc: Back ")
+
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(" Sync conflicts ")
+	for _, c := range conflicts {
+		list.AddItem(c.Path, "", 0, nil)
+	}
+
+	showConflict := func(index int) {
+		c := conflicts[index]
+		var text string
+		switch {
+		case c.Mine == "":
+			text = "[yellow]Deleted here, changed on the remote.[-]\n\n" + tview.Escape(c.Theirs)
+		case c.Theirs == "":
+			text = "[yellow]Changed here, deleted on the remote.[-]\n\n" + tview.Escape(c.Mine)
+		default:
+			text = colorDiff(diff.Unified(c.Mine, c.Theirs, "mine", "theirs", 3))
+		}
+		preview.SetText(text).ScrollToBeginning()
+	}
+
+	resolve := func(keepMine bool) {
+		c := conflicts[list.GetCurrentItem()]
+		report, err := repo.Resolve(c.Path, keepMine)
+		if err != nil {
+			preview.SetText(fmt.Sprintf("[red]Failed to resolve %s: %v", tview.Escape(c.Path), err))
+			return
+		}
+
+		ui.loadScripts()
+		if len(report.Conflicts) == 0 {
+			ui.inForm = false
+			ui.app.SetRoot(ui.root, true)
+			ui.details.SetText(fmt.Sprintf("[green]Conflicts resolved. Merged %d added, %d updated and %d deleted scripts. Run 'bashhub sync push' to share them.",
+				len(report.Added), len(report.Updated), len(report.Deleted)))
+			return
+		}
+		ui.showSyncConflicts()
+	}
+
+	list.SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+		showConflict(index)
+	})
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			ui.inForm = false
+			ui.app.SetRoot(ui.root, true)
+			return nil
+		case tcell.KeyPgDn:
+			row, col := preview.GetScrollOffset()
+			preview.ScrollTo(row+10, col)
+			return nil
+		case tcell.KeyPgUp:
+			row, col := preview.GetScrollOffset()
+			preview.ScrollTo(max(row-10, 0), col)
+			return nil
+		}
+		switch strings.ToLower(string(event.Rune())) {
+		case "m":
+			resolve(true)
+			return nil
+		case "t":
+			resolve(false)
+			return nil
+		}
+		return event
+	})
+
+	layout := tview.NewFlex().
+		AddItem(list, 0, 1, true).
+		AddItem(preview, 0, 2, false)
+
+	showConflict(0)
+	ui.app.SetRoot(layout, true).SetFocus(list)
+}
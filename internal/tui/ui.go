@@ -1,32 +1,47 @@
 package tui
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/jmoiron/sqlx"
+	"github.com/maccalsa/bashhub/internal/config"
 	"github.com/maccalsa/bashhub/internal/database"
 	"github.com/maccalsa/bashhub/internal/executor"
+	"github.com/maccalsa/bashhub/internal/secrets"
 	"github.com/rivo/tview"
 )
 
 type UI struct {
-	app     *tview.Application
-	db      *sqlx.DB
-	tree    *tview.TreeView
-	details *tview.TextView
-	root    tview.Primitive // root primitive
-	footer  *tview.TextView  // clearly added footer
-	inForm  bool
-	searching bool
-	searchBox *tview.InputField
+	app             *tview.Application
+	db              *sqlx.DB
+	cfg             config.Config
+	tree            *tview.TreeView
+	details         *tview.TextView
+	root            tview.Primitive // root primitive
+	footer          *tview.TextView // clearly added footer
+	inForm          bool
+	searching       bool
+	searchBox       *tview.InputField
 	searchContainer *tview.Flex
+	cancelRun       context.CancelFunc         // set while a script is running
+	secrets         *secrets.Store             // set once unlocked
+	screen          tcell.Screen               // set on the first draw
+	groupByTag      bool                       // group the tree by tag instead of category
+	collapsed       map[string]bool            // keys of the groups left collapsed
+	groupKeys       map[*tview.TreeNode]string // group nodes in the tree, by key
+	pinned          []*tview.TreeNode          // favorites and recent groups in the tree
+	favorites       map[int64]bool             // IDs of the favorite scripts
+	usage           map[int64]database.Usage   // run counts by script ID
 }
 
-func NewUI(db *sqlx.DB) *UI {
+func NewUI(db *sqlx.DB, cfg config.Config) *UI {
 	ui := &UI{
 		app:     tview.NewApplication(),
 		db:      db,
+		cfg:     cfg,
 		tree:    tview.NewTreeView(),
 		details: tview.NewTextView().SetDynamicColors(true),
 		searchBox: tview.NewInputField().
@@ -49,16 +64,21 @@ func NewUI(db *sqlx.DB) *UI {
 	ui.footer = tview.NewTextView()
 	ui.footer.SetDynamicColors(true).
 		SetTextAlign(tview.AlignCenter).
-		SetText("[yellow]Tab[white]: Switch Pane | [yellow]↑/↓[white]: Navigate Tree | [yellow]PgUp/PgDn[white]: Scroll Tree |\n [green]C[white]: Create | [blue]E[white]: Edit | [red]D[white]: Delete | [orange]X[white]: Execute | [cyan]Ctrl+Q[white]: Quit")
+		SetText("[yellow]Tab[white]: Switch Pane | [yellow]↑/↓[white]: Navigate Tree | [yellow]PgUp/PgDn[white]: Scroll Tree |\n [green]C[white]: Create | [blue]E[white]: Edit | [red]D[white]: Delete | [orange]X[white]: Execute | [purple]V[white]: Versions | [green]M[white]: Move | [yellow]F[white]: Favorite | [yellow]S[white]: Sync conflicts | [green]T[white]: Group by tag | [cyan]Ctrl+Q[white]: Quit")
 
 	ui.footer.SetBorder(true).SetBorderColor(tcell.ColorGray)
 
 	return ui
 }
 
-
 func (ui *UI) Run() error {
+	if err := ui.loadCollapsed(); err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Failed to load the tree state: %v", err))
+	}
 	ui.loadScripts()
+	if notice := ui.syncNotice(); notice != "" {
+		ui.details.SetText(notice)
+	}
 
 	ui.searchBox.SetBorder(true).SetBorderColor(tcell.ColorGray)
 
@@ -75,7 +95,18 @@ func (ui *UI) Run() error {
 
 	ui.app.SetFocus(ui.tree)
 
+	ui.app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		ui.screen = screen
+		return false
+	})
+
 	ui.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Ctrl+C cancels a running script instead of quitting bashhub
+		if event.Key() == tcell.KeyCtrlC && ui.cancelRun != nil {
+			ui.cancelRun()
+			return nil
+		}
+
 		if ui.inForm {
 			return event
 		}
@@ -147,6 +178,26 @@ func (ui *UI) Run() error {
 		case 'X', 'x':
 			ui.executeSelectedScript()
 			return nil
+		case 'V', 'v':
+			ui.showVersions()
+			return nil
+		case 'S', 's':
+			ui.showSyncConflicts()
+			return nil
+		case 'F', 'f':
+			ui.toggleFavorite()
+			return nil
+		case 'M', 'm':
+			ui.showMoveForm()
+			return nil
+		case 'T', 't':
+			ui.groupByTag = !ui.groupByTag
+			if query := ui.searchBox.GetText(); query != "" {
+				ui.filterScripts(query)
+			} else {
+				ui.loadScripts()
+			}
+			return nil
 		}
 
 		return event
@@ -170,16 +221,32 @@ func (ui *UI) executeSelectedScript() {
 
 	script := ref.(database.Script)
 
-	placeholders := executor.ParsePlaceholders(script.Content)
-	if len(placeholders) > 0 {
+	placeholders, err := executor.ParsePlaceholders(script.Content)
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Invalid placeholder: %v", err))
+		return
+	}
+	if secrets.NeedsSecrets(placeholders) {
+		ui.unlockSecrets(func() {
+			ui.promptPlaceholderInputs(script, placeholders)
+		})
+	} else if len(placeholders) > 0 {
 		ui.promptPlaceholderInputs(script, placeholders)
 	} else {
-		ui.runAndDisplay(script.Content)
+		ui.confirmDangerous(script, script.Content, nil, func() {
+			ui.runAndDisplay(script, script.Content, nil, nil)
+		})
 	}
 }
 
+// runAndDisplay runs the script with its placeholders substituted, streaming
+// the output with sensitive values masked.
+func (ui *UI) runAndDisplay(script database.Script, scriptContent string, placeholders []executor.Placeholder, inputs map[string]string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelRun = cancel
+	ui.inForm = true
+	left := false // only touched on the UI goroutine
 
-func (ui *UI) runAndDisplay(scriptContent string) {
 	outputView := tview.NewTextView()
 	outputView.
 		SetDynamicColors(true).
@@ -192,7 +259,7 @@ func (ui *UI) runAndDisplay(scriptContent string) {
 			})
 		})
 
-	outputView.SetBorder(true).SetTitle("Execution Output (↑/↓ Scroll) | Press Q to Quit").SetBorderColor(tcell.ColorGreen)
+	outputView.SetBorder(true).SetTitle("Execution Output (↑/↓ Scroll) | Ctrl+C to Cancel | Press Q to Quit").SetBorderColor(tcell.ColorGreen)
 
 	outputView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		row, col := outputView.GetScrollOffset()
@@ -218,6 +285,12 @@ func (ui *UI) runAndDisplay(scriptContent string) {
 		}
 
 		if event.Rune() == 'q' || event.Rune() == 'Q' {
+			// Leaving the output view also stops the script
+			cancel()
+			left = true
+			ui.cancelRun = nil
+			ui.inForm = false
+			ui.refreshPinned() // the run moves the script up in Recent
 			ui.app.SetRoot(ui.root, true)
 			return nil
 		}
@@ -226,21 +299,55 @@ func (ui *UI) runAndDisplay(scriptContent string) {
 	})
 
 	go func() {
-		err := executor.ExecuteScript(scriptContent, func(line string) {
-			ui.app.QueueUpdateDraw(func() {
-				fmt.Fprintln(outputView, tview.TranslateANSI(line))
-				outputView.ScrollToEnd() // Automatically scroll to the end
-			})
+		defer cancel()
+		runID, recordErr := database.StartRun(ui.db, script.ID, executor.RedactInputs(placeholders, inputs))
+
+		var output database.OutputTail
+		interpreter := executor.ResolveInterpreter(script.Content, script.Language, script.Interpreter, ui.cfg.Interpreters)
+		result, err := executor.Run(ctx, scriptContent, executor.Options{
+			Interpreter: interpreter,
+			Name:        script.Name,
+			PTY:         true,
+			Mask:        executor.SensitiveValues(placeholders, inputs),
+			Output: func(chunk string) {
+				output.Write(chunk)
+				ui.app.QueueUpdateDraw(func() {
+					fmt.Fprint(outputView, tview.TranslateANSI(chunk))
+					outputView.ScrollToEnd() // Automatically scroll to the end
+				})
+			},
 		})
+
+		exitCode := result.ExitCode
 		if err != nil {
-			ui.app.QueueUpdateDraw(func() {
-				fmt.Fprintf(outputView, "[red]Execution failed: %v", err)
-				outputView.ScrollToEnd()
-			})
+			exitCode = database.ExitCodeError
+			output.Write(err.Error())
+		}
+		if recordErr == nil {
+			recordErr = database.FinishRun(ui.db, runID, exitCode, output.String())
 		}
+
+		ui.app.QueueUpdateDraw(func() {
+			if !left {
+				ui.cancelRun = nil
+			}
+
+			switch {
+			case err != nil:
+				fmt.Fprintf(outputView, "\n[red]Execution failed: %v", err)
+			case result.Killed:
+				fmt.Fprintf(outputView, "\n[yellow]Cancelled after %s (exit code %d)", result.Duration.Round(time.Millisecond), result.ExitCode)
+			case result.ExitCode != 0:
+				fmt.Fprintf(outputView, "\n[red]Failed with exit code %d after %s", result.ExitCode, result.Duration.Round(time.Millisecond))
+			default:
+				fmt.Fprintf(outputView, "\n[green]Finished in %s", result.Duration.Round(time.Millisecond))
+			}
+			if recordErr != nil {
+				fmt.Fprintf(outputView, "\n[yellow]Failed to record run: %v", recordErr)
+			}
+			outputView.ScrollToEnd()
+		})
 	}()
 
 	ui.app.SetRoot(outputView, true)
 }
-
-
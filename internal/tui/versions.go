@@ -0,0 +1,147 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/maccalsa/bashhub/internal/database"
+	"github.com/maccalsa/bashhub/internal/diff"
+	"github.com/rivo/tview"
+)
+
+// showVersions lists the versions of the selected script with a diff of the
+// changes each one introduced. Enter rolls back to the highlighted version.
+func (ui *UI) showVersions() {
+	node := ui.tree.GetCurrentNode()
+	if node == nil {
+		return
+	}
+
+	ref := node.GetReference()
+	if ref == nil {
+		ui.details.SetText("[red]Please select a script to view its versions.")
+		return
+	}
+
+	script := ref.(database.Script)
+
+	versions, err := database.GetScriptVersions(ui.db, script.ID)
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Failed to load versions: %v", err))
+		return
+	}
+	if len(versions) == 0 {
+		ui.details.SetText("[yellow]No versions recorded yet.")
+		return
+	}
+
+	ui.inForm = true
+	preview := tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	preview.SetBorder(true).SetTitle(" Changes ")
+
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(fmt.Sprintf(" Versions of %s | Enter: Roll back | Esc: Back ", script.Name))
+
+	// Newest first
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		list.AddItem(fmt.Sprintf("v%d  %s  %s", v.Version, v.CreatedAt.Local().Format("2006-01-02 15:04"), v.Author), "", 0, nil)
+	}
+
+	versionAt := func(index int) (database.ScriptVersion, string) {
+		i := len(versions) - 1 - index
+		previous := ""
+		if i > 0 {
+			previous = versions[i-1].Content
+		}
+		return versions[i], previous
+	}
+
+	showChanges := func(index int) {
+		v, previous := versionAt(index)
+		unified := diff.Unified(previous, v.Content, fmt.Sprintf("v%d", v.Version-1), fmt.Sprintf("v%d", v.Version), 3)
+		if unified == "" {
+			unified = "No content changes (description only).\n"
+		}
+		preview.SetText(colorDiff(unified)).ScrollToBeginning()
+	}
+
+	list.SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+		showChanges(index)
+	})
+
+	list.SetSelectedFunc(func(index int, _ string, _ string, _ rune) {
+		v, _ := versionAt(index)
+		ui.confirmRollback(script, v)
+	})
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			ui.inForm = false
+			ui.app.SetRoot(ui.root, true)
+			return nil
+		case tcell.KeyPgDn:
+			row, col := preview.GetScrollOffset()
+			preview.ScrollTo(row+10, col)
+			return nil
+		case tcell.KeyPgUp:
+			row, col := preview.GetScrollOffset()
+			preview.ScrollTo(max(row-10, 0), col)
+			return nil
+		}
+		return event
+	})
+
+	layout := tview.NewFlex().
+		AddItem(list, 0, 1, true).
+		AddItem(preview, 0, 2, false)
+
+	// Show the changes of the newest version straight away
+	showChanges(0)
+
+	ui.app.SetRoot(layout, true).SetFocus(list)
+}
+
+func (ui *UI) confirmRollback(script database.Script, version database.ScriptVersion) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Roll back '%s' to version %d?", script.Name, version.Version)).
+		AddButtons([]string{"Cancel", "Roll back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Roll back" {
+				if _, err := database.RollbackScript(ui.db, script, version.Version); err != nil {
+					ui.details.SetText(fmt.Sprintf("[red]Failed to roll back: %v", err))
+				} else {
+					ui.loadScripts()
+					ui.details.SetText(fmt.Sprintf("[green]Rolled back to version %d.", version.Version))
+				}
+			}
+			ui.inForm = false
+			ui.app.SetRoot(ui.root, true)
+		})
+
+	ui.app.SetRoot(modal, false)
+}
+
+// colorDiff escapes a unified diff and colours its lines for a TextView.
+func colorDiff(unified string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(unified, "\n"), "\n") {
+		escaped := tview.Escape(line)
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			b.WriteString("[::b]" + escaped + "[::-]")
+		case strings.HasPrefix(line, "@@"):
+			b.WriteString("[aqua]" + escaped + "[-]")
+		case strings.HasPrefix(line, "+"):
+			b.WriteString("[green]" + escaped + "[-]")
+		case strings.HasPrefix(line, "-"):
+			b.WriteString("[red]" + escaped + "[-]")
+		default:
+			b.WriteString(escaped)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}